@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// commands are the subcommands. They are given the arguments after their
+// name; any other first argument starts a conversion.
+var commands = map[string]func(args []string) error{
+	"export":   runExport,
+	"graph":    runGraph,
+	"history":  runHistory,
+	"serve":    runServe,
+	"validate": runValidate,
+}
+
+// usage prints how to run a conversion and lists the subcommands.
+func usage() {
+	name := os.Args[0]
+	out := flag.CommandLine.Output()
+
+	fmt.Fprintf(out, "Usage: %s -i export.json -d vault [flags]\n", name)
+	fmt.Fprintf(out, "       %s %s -i export.json -page title [flags]\n", name, previewCommand)
+	fmt.Fprintf(out, "       %s %s -i export.json -d vault [flags]\n", name, tuiCommand)
+
+	names := make([]string, 0, len(commands))
+	for cmd := range commands {
+		names = append(names, cmd)
+	}
+	sort.Strings(names)
+	for _, cmd := range names {
+		fmt.Fprintf(out, "       %s %s [flags]\n", name, cmd)
+	}
+
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+
+	fmt.Fprintf(out, "\nEvery flag can also be set with an environment variable, such as %s for -i,\n", envName("i"))
+	fmt.Fprintf(out, "%s for -d or %s for -skip-empty. Flags given on the command line win.\n", envName("d"), envName("skip-empty"))
+}
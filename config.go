@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/bryanl/goram2obs/pkg/convert"
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// fileConfig is the JSON configuration file given with -config.
+type fileConfig struct {
+	DailyPatterns []*roam.DailyPattern   `json:"daily_patterns"`
+	Replace       []*convert.Replacement `json:"replace"`
+	Exclude       []string               `json:"exclude"`
+	Include       []string               `json:"include"`
+}
+
+func loadConfig(configPath string) (*fileConfig, error) {
+	cfg := &fileConfig{}
+	if configPath == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+
+	for _, p := range cfg.DailyPatterns {
+		if err := p.Compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, r := range cfg.Replace {
+		if err := r.Compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envPrefix starts the names of the environment variables that set the
+// conversion flags.
+const envPrefix = "GOROAM2OBS_"
+
+// envNames are the variable names of the flags too short to name one.
+var envNames = map[string]string{
+	"i":  "INPUT",
+	"d":  "OUTDIR",
+	"o":  "OUTZIP",
+	"v":  "VERBOSE",
+	"vv": "VERY_VERBOSE",
+}
+
+// envName returns the environment variable that sets the flag name, such as
+// GOROAM2OBS_SKIP_EMPTY for -skip-empty.
+func envName(name string) string {
+	if n, ok := envNames[name]; ok {
+		return envPrefix + n
+	}
+
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnv sets the flags of fs that weren't given on the command line from
+// their environment variables. A repeatable flag takes a value per line.
+func applyEnv(fs *flag.FlagSet) error {
+	given := map[string]struct{}{}
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = struct{}{}
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, ok := given[f.Name]; ok || err != nil {
+			return
+		}
+
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+
+		values := []string{value}
+		if _, ok := f.Value.(*repeatedFlag); ok {
+			values = strings.Split(strings.TrimRight(value, "\n"), "\n")
+		}
+		for _, v := range values {
+			if serr := fs.Set(f.Name, v); serr != nil {
+				err = fmt.Errorf("invalid %s: %w", envName(f.Name), serr)
+				return
+			}
+		}
+	})
+
+	return err
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bryanl/goram2obs/pkg/vault"
+)
+
+// runExport rebuilds a Roam JSON export from a converted vault.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dir := fs.String("d", "", "Vault directory")
+	out := fs.String("o", "", "Roam JSON file to write (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *dir == "" {
+		return errors.New("vault directory is blank")
+	}
+
+	pages, err := vault.Export(*dir)
+	if err != nil {
+		return fmt.Errorf("export %s: %w", *dir, err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(pages); err != nil {
+		return fmt.Errorf("write %s: %w", *out, err)
+	}
+
+	return nil
+}
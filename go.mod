@@ -2,14 +2,19 @@ module github.com/bryanl/goram2obs
 
 go 1.17
 
+require (
+	filippo.io/age v1.0.0
+	github.com/cheggaaa/pb/v3 v3.0.8
+	github.com/yuin/goldmark v1.4.10
+)
+
 require (
 	github.com/VividCortex/ewma v1.1.1 // indirect
-	github.com/cheggaaa/pb/v3 v3.0.8 // indirect
-	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fatih/color v1.10.0 // indirect
 	github.com/mattn/go-colorable v0.1.8 // indirect
 	github.com/mattn/go-isatty v0.0.12 // indirect
 	github.com/mattn/go-runewidth v0.0.12 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	golang.org/x/sys v0.0.0-20210403161142-5e06dd20ab57 // indirect
+	golang.org/x/crypto v0.0.0-20210817164053-32db794688a5 // indirect
+	golang.org/x/sys v0.0.0-20210903071746-97244b99971b // indirect
 )
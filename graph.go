@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/bryanl/goram2obs/pkg/convert"
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// runGraph analyzes the links in an export without converting it.
+func runGraph(args []string) error {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	input := fs.String("i", "", "Input file")
+	format := fs.String("format", "", "Input format (default detected from the file)")
+	top := fs.Int("top", 10, "Show the top n most linked and largest pages, 0 for all")
+	hub := fs.String("hub", "", "Measure link depth from this page")
+	asJSON := fs.Bool("json", false, "Print the analysis as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *input == "" {
+		return errors.New("input is blank")
+	}
+
+	pages, err := roam.Importers.Import(*input, *format)
+	if err != nil {
+		return fmt.Errorf("load input: %w", err)
+	}
+
+	r := convert.Analyze(pages, *top, *hub)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d pages, %d blocks\n", r.Pages, r.Blocks)
+
+	fmt.Fprintf(tw, "\nMost linked pages\n")
+	for _, pc := range r.MostLinked {
+		fmt.Fprintf(tw, "  %d\t%s\n", pc.Count, pc.Page)
+	}
+
+	fmt.Fprintf(tw, "\nLargest pages (blocks)\n")
+	for _, pc := range r.Largest {
+		fmt.Fprintf(tw, "  %d\t%s\n", pc.Count, pc.Page)
+	}
+
+	fmt.Fprintf(tw, "\nOrphan pages (%d)\n", len(r.Orphans))
+	for _, title := range r.Orphans {
+		fmt.Fprintf(tw, "  %s\n", title)
+	}
+
+	fmt.Fprintf(tw, "\nBroken links (%d)\n", len(r.Broken))
+	for _, b := range r.Broken {
+		fmt.Fprintf(tw, "  %s\tfrom %d pages\n", b.Target, len(b.From))
+	}
+
+	if r.Hub != "" && len(r.Depth) == 0 {
+		fmt.Fprintf(tw, "\nLink depth: page %q not found\n", r.Hub)
+	} else if r.Hub != "" {
+		byDepth := map[int]int{}
+		for _, d := range r.Depth {
+			byDepth[d]++
+		}
+		depths := make([]int, 0, len(byDepth))
+		for d := range byDepth {
+			depths = append(depths, d)
+		}
+		sort.Ints(depths)
+
+		fmt.Fprintf(tw, "\nLink depth from %s\n", r.Hub)
+		for _, d := range depths {
+			fmt.Fprintf(tw, "  %d\t%d pages\n", d, byDepth[d])
+		}
+		fmt.Fprintf(tw, "  unreachable\t%d pages\n", r.Pages-len(r.Depth))
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bryanl/goram2obs/pkg/convert"
+)
+
+// historyEntry is a conversion run recorded in the history file.
+type historyEntry struct {
+	Time    time.Time         `json:"time"`
+	Input   string            `json:"input"`
+	Output  string            `json:"output"`
+	Options map[string]string `json:"options,omitempty"`
+	// DurationMS is the length of the run and PhaseMS of each conversion
+	// pass, in milliseconds.
+	DurationMS int64            `json:"duration_ms"`
+	PhaseMS    map[string]int64 `json:"phase_ms,omitempty"`
+	Stats      convert.Stats    `json:"stats"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// defaultHistoryPath returns the history file in the user's config
+// directory. Nothing in it leaves the machine.
+func defaultHistoryPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "goroam2obs", "history.jsonl"), nil
+}
+
+// appendHistory adds entry to the history file at historyPath.
+func appendHistory(historyPath string, entry historyEntry) error {
+	if err := os.MkdirAll(filepath.Dir(historyPath), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// readHistory reads the entries of the history file at historyPath, oldest
+// first. A missing file has no entries.
+func readHistory(historyPath string) ([]historyEntry, error) {
+	f, err := os.Open(historyPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var entry historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", historyPath, line, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// secretFlags hold credentials, which aren't recorded.
+var secretFlags = map[string]struct{}{
+	"token":                    {},
+	"roam-encryption-password": {},
+}
+
+// setFlags returns the flags given on the command line.
+func setFlags() map[string]string {
+	options := map[string]string{}
+	flag.Visit(func(f *flag.Flag) {
+		options[f.Name] = f.Value.String()
+		if _, ok := secretFlags[f.Name]; ok {
+			options[f.Name] = "redacted"
+		}
+	})
+
+	return options
+}
+
+// runHistory prints the recorded conversion runs.
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	historyFile := fs.String("file", "", "History file (defaults to the one in the user config directory)")
+	n := fs.Int("n", 20, "Show the last n runs, 0 for all")
+	asJSON := fs.Bool("json", false, "Print the runs as JSON lines")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	historyPath := *historyFile
+	if historyPath == "" {
+		var err error
+		if historyPath, err = defaultHistoryPath(); err != nil {
+			return err
+		}
+	}
+
+	entries, err := readHistory(historyPath)
+	if err != nil {
+		return err
+	}
+	if *n > 0 && len(entries) > *n {
+		entries = entries[len(entries)-*n:]
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, entry := range entries {
+			if err := enc.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tDURATION\tPAGES\tWARNINGS\tUNRESOLVED\tINPUT\tOPTIONS")
+	for _, entry := range entries {
+		input := entry.Input
+		if entry.Error != "" {
+			input += " (failed: " + entry.Error + ")"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n",
+			entry.Time.Local().Format("2006-01-02 15:04:05"),
+			(time.Duration(entry.DurationMS) * time.Millisecond).String(),
+			entry.Stats.Pages,
+			entry.Stats.Warnings,
+			entry.Stats.BlockRefsUnresolved,
+			input,
+			formatOptions(entry.Options))
+	}
+
+	return tw.Flush()
+}
+
+// formatOptions formats options as name=value pairs sorted by name.
+func formatOptions(options map[string]string) string {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = "-" + name + "=" + options[name]
+	}
+
+	return strings.Join(parts, " ")
+}
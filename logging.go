@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+type logLevel int
+
+const (
+	levelError logLevel = iota
+	levelWarn
+	levelInfo
+	levelDebug
+	levelTrace
+)
+
+func (l logLevel) String() string {
+	switch l {
+	case levelError:
+		return "error"
+	case levelWarn:
+		return "warn"
+	case levelInfo:
+		return "info"
+	case levelDebug:
+		return "debug"
+	default:
+		return "trace"
+	}
+}
+
+// logger writes leveled log lines as text or JSON. Messages are followed by
+// key value pairs.
+type logger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	level logLevel
+	json  bool
+	// page limits debug and trace messages about pages to matching titles.
+	page *regexp.Regexp
+}
+
+func newLogger(out io.Writer, level logLevel, asJSON bool, page *regexp.Regexp) *logger {
+	return &logger{out: out, level: level, json: asJSON, page: page}
+}
+
+func (l *logger) Error(msg string, kv ...interface{}) { l.log(levelError, msg, kv) }
+func (l *logger) Warn(msg string, kv ...interface{})  { l.log(levelWarn, msg, kv) }
+func (l *logger) Info(msg string, kv ...interface{})  { l.log(levelInfo, msg, kv) }
+func (l *logger) Debug(msg string, kv ...interface{}) { l.log(levelDebug, msg, kv) }
+func (l *logger) Trace(msg string, kv ...interface{}) { l.log(levelTrace, msg, kv) }
+
+// Enabled reports whether messages at level are written.
+func (l *logger) Enabled(level logLevel) bool {
+	return level <= l.level
+}
+
+func (l *logger) log(level logLevel, msg string, kv []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	if level >= levelDebug && l.page != nil {
+		for i := 0; i+1 < len(kv); i += 2 {
+			if kv[i] == "page" && !l.page.MatchString(fmt.Sprint(kv[i+1])) {
+				return
+			}
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.json {
+		entry := map[string]interface{}{
+			"time":  time.Now().Format(time.RFC3339),
+			"level": level.String(),
+			"msg":   msg,
+		}
+		for i := 0; i+1 < len(kv); i += 2 {
+			entry[fmt.Sprint(kv[i])] = kv[i+1]
+		}
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			data = []byte(fmt.Sprintf(`{"level":"error","msg":%q}`, err.Error()))
+		}
+		fmt.Fprintln(l.out, string(data))
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(strings.ToUpper(level.String()))
+	sb.WriteString(" ")
+	sb.WriteString(msg)
+	for i := 0; i+1 < len(kv); i += 2 {
+		fmt.Fprintf(&sb, " %v=%s", kv[i], quoteLogValue(fmt.Sprint(kv[i+1])))
+	}
+	fmt.Fprintln(l.out, sb.String())
+}
+
+func quoteLogValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+
+	return s
+}
@@ -5,447 +5,757 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
-	"github.com/cheggaaa/pb/v3"
+	"filippo.io/age"
+
+	"github.com/bryanl/goram2obs/pkg/convert"
+	"github.com/bryanl/goram2obs/pkg/roam"
+	"github.com/bryanl/goram2obs/pkg/vault"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	var ac appConfig
-	flag.StringVar(&ac.input, "i", "", "Input file")
-	flag.StringVar(&ac.outDir, "d", "", "Output directory")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case previewCommand:
+			ac.preview = true
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+		case tuiCommand:
+			ac.tui = true
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+		}
+	}
+
+	flag.Usage = usage
+	ac.addFlags(flag.CommandLine)
 	flag.Parse()
 
-	if err := run(ac); err != nil {
-		log.Print(err)
+	var lg *logger
+	err := applyEnv(flag.CommandLine)
+	if err == nil {
+		lg, err = ac.logger()
+	}
+	if err == nil {
+		if ac.tui {
+			err = runTUI(&ac, lg)
+		} else {
+			err = run(ac, lg)
+		}
+	}
+
+	if err != nil {
+		if lg == nil {
+			lg = newLogger(os.Stderr, levelError, ac.logFormat == logFormatJSON, nil)
+		}
+		lg.Error(err.Error())
 		flag.Usage()
 		os.Exit(1)
 	}
 }
 
-func run(ac appConfig) error {
+// addFlags defines the conversion flags on fs, storing their values in ac.
+func (ac *appConfig) addFlags(fs *flag.FlagSet) {
+	fs.StringVar(&ac.input, "i", "", "Input file, - to read it from stdin, or a roam-to-git backup directory or git URL")
+	fs.StringVar(&ac.graph, "graph", "", "Read this graph with the Roam API instead of an export (see -token)")
+	fs.StringVar(&ac.token, "token", "", "Roam graph API token for -graph (default $"+roamTokenEnv+")")
+	fs.StringVar(&ac.format, "format", "", "Input format: "+strings.Join(roam.Importers.Names(), ", ")+" (default detected from the file)")
+	fs.StringVar(&ac.outDir, "d", "", "Output directory")
+	fs.StringVar(&ac.outZip, "o", "", "Write the vault into this zip file instead of a directory")
+	fs.StringVar(&ac.watch, "watch", "", "Watch this directory for new JSON or zip exports and convert each one")
+	fs.DurationVar(&ac.watchInterval, "watch-interval", 2*time.Second, "How often -watch checks for new exports")
+	fs.StringVar(&ac.target, "target", convert.TargetObsidian, "Output format: obsidian, logseq or html")
+	fs.StringVar(&ac.config, "config", "", "JSON configuration file")
+	fs.Var(&ac.dailyPatterns, "daily-pattern", "Regular expression with year, month and day groups matching extra daily note titles (repeatable)")
+	fs.Var(&ac.dailyLocales, "daily-locale", "Comma separated languages whose daily note titles, such as \"3 de enero de 2022\", are also detected: "+strings.Join(roam.DateLocaleNames(), ", "))
+	fs.StringVar(&ac.annotations, "annotations", "", "JSON file of block annotations keyed by block UID")
+	fs.StringVar(&ac.annotationStyle, "annotation-style", convert.AnnotationFootnote, "How annotations are merged: footnote or callout")
+	fs.StringVar(&ac.hiccupFallback, "hiccup-fallback", convert.HiccupPassthrough, "What to do with unsupported :hiccup blocks: passthrough or strip")
+	fs.StringVar(&ac.userMap, "user-map", "", "JSON file mapping user emails or uids to display names")
+	fs.BoolVar(&ac.linkEmails, "link-emails", false, "Link bare email addresses to person pages")
+	fs.StringVar(&ac.renderPolicy, "render", convert.RenderCallout, "How roam/render components are handled: strip, callout or report")
+	fs.StringVar(&ac.dayStyle, "day-style", convert.DayStyleObsidian, "Daily note title style: obsidian (2006-01-02) or roam (January 2nd, 2006)")
+	fs.BoolVar(&ac.disambiguate, "disambiguate", false, "Add a numeric suffix to pages whose filenames only differ by case (same as -on-collision suffix)")
+	fs.StringVar(&ac.onCollision, "on-collision", "", "What happens to pages that map to the same filename: warn (default), error, suffix (rename all but the first) or merge (one note with a section per page)")
+	fs.BoolVar(&ac.skipEmpty, "skip-empty", false, "Don't write pages without any content")
+	fs.BoolVar(&ac.skipOrphans, "skip-orphans", false, "Don't write non-daily pages that no other page links to")
+	fs.IntVar(&ac.maxFiles, "max-files", 50000, "Warn when the vault would have more notes than this, 0 to disable")
+	fs.Var(&ac.shard, "shard", "Comma separated strategies applied when -max-files is exceeded: "+strings.Join(convert.ShardStrategies, ", "))
+	fs.StringVar(&ac.wrapperMode, "code-wrappers", convert.WrapperFence, "How components holding code (htmlview, roam/css, roam/js) are written: fence or snippet")
+	fs.BoolVar(&ac.stripTitleEmoji, "strip-title-emoji", false, "Remove leading emoji from filenames and keep the original title as an alias")
+	fs.BoolVar(&ac.slug, "slug", false, "Write pages to kebab-case ASCII filenames, keeping the original title in frontmatter and as link text")
+	fs.StringVar(&ac.style, "style", convert.StyleIndent, "Block layout: indent, outline (every block is a list item) or prose (paragraphs)")
+	fs.StringVar(&ac.nestedHeadings, "nested-headings", convert.HeadingBold, "How nested heading blocks are written: bold or keep (a heading outside the list)")
+	fs.StringVar(&ac.bullet, "bullet", "", "List marker: -, *, + or 1. to number items (default - for outline and prose, * for indent)")
+	fs.StringVar(&ac.indent, "indent", "", "What each nesting level is indented by: tab or a number of spaces (default 4 spaces for indent, a tab for outline and prose)")
+	fs.BoolVar(&ac.tagsToEnd, "tags-to-end", false, "Move tags to the end of their block and remove duplicates")
+	fs.StringVar(&ac.slashTags, "slash-tags", convert.SlashTagsTag, "How tags with a slash such as #projects/alpha are written: tag (nested tag), link (to the namespaced page) or both")
+	fs.StringVar(&ac.quotes, "quotes", convert.QuotesPlain, "How quote blocks (\"> \" or [[>]]) are written: plain (blockquote) or callout (a quote callout holding the block's children)")
+	fs.StringVar(&ac.rules, "rules", "", "JSON file of ordered find and replace rules, applied after the config's replace rules")
+	fs.BoolVar(&ac.dryRun, "dry-run", false, "Log the changes made by replace rules without writing any files")
+	fs.StringVar(&ac.folderIndex, "folder-index", "", "Write an index of the notes in every folder: readme (README.md) or about (_about.md)")
+	fs.Var(&ac.canvasFor, "canvas-for", "Experimental: also lay out this page's blocks and links as an Obsidian canvas (repeatable)")
+	fs.StringVar(&ac.historyFile, "history-file", "", "Record each run in this file instead of the one in the user config directory")
+	fs.BoolVar(&ac.noHistory, "no-history", false, "Don't record the run in the history file shown by the history command")
+	fs.Var(&ac.encrypt, "encrypt", "Encrypt every output file to age:<recipient> (repeatable for several recipients)")
+	fs.StringVar(&ac.stats, "stats", "", "Also write the conversion statistics as JSON to this file")
+	fs.Var(&ac.exclude, "exclude", "Skip pages whose whole title matches this regular expression (repeatable)")
+	fs.Var(&ac.include, "include", "Only convert pages whose whole title matches this regular expression (repeatable)")
+	fs.BoolVar(&ac.includeLinked, "include-linked", false, "With -include, also convert the pages that included pages link to")
+	fs.Var(&ac.pages, "page", "Only convert the page with this exact title, resolving block refs against the whole export (repeatable)")
+	fs.BoolVar(&ac.uidMap, "uid-map", false, "Write uid-map.json mapping page titles to notes and block UIDs to their anchors")
+	fs.BoolVar(&ac.backlinksSection, "backlinks-section", false, "Append a Backlinks section to every note listing the blocks that link to it")
+	fs.StringVar(&ac.moc, "moc", "", "Write an Index note of all pages grouped by letter or namespace, with a hub note per namespace")
+	fs.BoolVar(&ac.initVault, "init-vault", false, "Write the .obsidian settings of a ready to use vault (attachment and template folders, daily notes, core plugins)")
+	fs.BoolVar(&ac.roamCodePages, "roam-code-pages", false, "Write the code of roam/css pages to .obsidian/snippets and of roam/js pages to roam-js/ instead of converting them as notes")
+	fs.BoolVar(&ac.anki, "anki", false, "Also write the blocks tagged #anki or #flashcard, with their children as the answer, to flashcards.tsv for importing into Anki")
+	fs.BoolVar(&ac.templates, "templates", false, "Write each template on the roam/templates page to the templates folder used by the Templates core plugin")
+	fs.BoolVar(&ac.dailyNotesConfig, "daily-notes-config", false, "Write .obsidian/daily-notes.json so the Daily Notes plugin uses the converted daily folder and format")
+	fs.StringVar(&ac.bareDates, "bare-dates", "", "Rewrite dates like \"January 3rd, 2022\" in plain text: text (to the daily note title) or link (to the daily note)")
+	fs.StringVar(&ac.dailyFrom, "daily-from", "", "Skip daily pages before this date (YYYY-MM-DD)")
+	fs.StringVar(&ac.dailyTo, "daily-to", "", "Skip daily pages after this date (YYYY-MM-DD)")
+	fs.StringVar(&ac.skippedRefs, "skipped-refs", convert.SkippedRefsLink, "How refs to blocks on skipped pages are written: link or text (without a link)")
+	fs.StringVar(&ac.missingRefs, "missing-refs", convert.MissingRefsPlaceholder, "How refs to blocks that aren't in the export are written: placeholder ([missing block: uid] #roam-missing-ref) or keep")
+	fs.BoolVar(&ac.unlinkExcluded, "unlink-excluded", false, "Turn links to pages skipped by -exclude or -include into plain text")
+	fs.StringVar(&ac.reactions, "reactions", convert.ReactionsDrop, "How emoji reactions are written: drop, comment (after the block) or frontmatter (counts per page)")
+	fs.StringVar(&ac.textAlign, "text-align", convert.AlignStrip, "How centered and right-aligned blocks are written: strip or html (a div setting text-align)")
+	fs.IntVar(&ac.maxRefDepth, "max-ref-depth", 10, "How deep block refs inside referenced blocks are expanded")
+	fs.BoolVar(&ac.authorCallouts, "author-callouts", false, "Wrap blocks written by someone other than the page's creator in a callout naming them (see -user-map)")
+	fs.BoolVar(&ac.authorFrontmatter, "author-frontmatter", false, "Add the name of the page's creator to its frontmatter as author (see -user-map)")
+	fs.BoolVar(&ac.authorSuffix, "author-suffix", false, "End blocks written by someone other than the page's creator with \"— name\" (see -user-map)")
+	fs.BoolVar(&ac.keepAliasBlocks, "keep-alias-blocks", false, "Keep alias:: blocks after adding their values to the page aliases")
+	fs.StringVar(&ac.pageTags, "page-tags", "", "Add the tags of a first block holding only tags to the frontmatter: copy (keep the block) or move (remove it)")
+	fs.BoolVar(&ac.mobileSafe, "mobile-safe", false, "Write a vault for Obsidian Mobile: no HTML or iframes, short filenames and shallow folders")
+	fs.StringVar(&ac.tz, "tz", "", "IANA time zone, such as Europe/Berlin, that Roam timestamps are shown in (default local)")
+	fs.StringVar(&ac.queries, "queries", convert.QueryCallout, "How :q datalog query blocks are written: callout (collapsed, for manual migration), keep or strip")
+	fs.StringVar(&ac.smartBlocks, "smartblocks", convert.SmartBlocksKeep, "How SmartBlocks workflow syntax is written: keep, strip or text (buttons become their label, commands inline code)")
+	fs.StringVar(&ac.srs, "srs", convert.SRSKeep, "How {{[[∆]]: n+m}} spaced repetition blocks are written: keep or flashcards (Spaced Repetition plugin cards with their due date)")
+	fs.StringVar(&ac.tasks, "tasks", convert.TasksKeep, "How {{[[TODO]]}} and {{[[DONE]]}} blocks are written: keep or plugin (Obsidian Tasks checkboxes with their date link as the due or done date)")
+	fs.StringVar(&ac.iframes, "iframes", "", "How {{iframe}} components are written: html (default), sandbox (an iframe that can't run scripts) or link (default with -mobile-safe)")
+	fs.Var(&ac.widgets, "widgets", "How widgets are written, as name=mode pairs separated by commas; modes are strip, text, html or keep (widgets: "+strings.Join(convert.WidgetNames(), ", ")+")")
+	fs.StringVar(&ac.encryptedBlocks, "encrypted-blocks", "", "How {{encrypt}} blocks are written: strip, callout (default) or decrypt")
+	fs.StringVar(&ac.encryptionPassword, "roam-encryption-password", "", "Password to decrypt {{encrypt}} blocks with (implies -encrypted-blocks decrypt)")
+	fs.BoolVar(&ac.safe, "safe", false, "Quarantine pages that fail to convert instead of aborting (same as -on-error warn)")
+	fs.StringVar(&ac.onError, "on-error", "", "What happens to a page that fails to convert: fail (default), warn (quarantine it) or skip")
+	fs.BoolVar(&ac.resume, "resume", false, "Continue an interrupted conversion into the same output, skipping the pages it already wrote. Assets such as drawings, snippets and canvases, and the pages they come from, are always written again")
+	fs.BoolVar(&ac.strict, "strict", false, "Fail on problems that are otherwise only reported, such as unresolved block references and filename collisions")
+	fs.BoolVar(&ac.verbose, "v", false, "Log debug messages")
+	fs.BoolVar(&ac.veryVerbose, "vv", false, "Log debug and trace messages")
+	fs.BoolVar(&ac.quiet, "quiet", false, "Only log errors and hide the progress bars")
+	fs.StringVar(&ac.progress, "progress", progressModeBar, "How progress is shown on stderr: bar, json (a line of JSON per step) or none")
+	fs.StringVar(&ac.logFormat, "log-format", logFormatText, "Log format: text or json")
+	fs.StringVar(&ac.logPage, "log-page", "", "Only log debug and trace messages for pages whose title matches this regular expression")
+}
+
+func run(ac appConfig, lg *logger) error {
 	if err := ac.Validate(); err != nil {
 		return fmt.Errorf("invalid config: %w", err)
 	}
 
-	pages, err := loadJSON(ac.input)
+	opts, err := ac.options(lg)
 	if err != nil {
-		return fmt.Errorf("load JSON: %w", err)
+		return err
 	}
 
-	for i := range pages {
-		for j := range pages[i].Children() {
-			pages[i].RawChildren[j].Page = pages[i]
-		}
+	if ac.preview {
+		return preview(ac, opts)
 	}
 
-	uidBlock, err := pass1(pages)
-	if err != nil {
-		return fmt.Errorf("pass1: %w", err)
+	if ac.watch != "" {
+		return watch(ac, lg, opts)
 	}
 
-	referencedUID := map[string]struct{}{}
-	if err := pass2(pages, uidBlock, referencedUID); err != nil {
-		return fmt.Errorf("pass2: %w", err)
-	}
-
-	return pass3(pages, uidBlock, referencedUID, ac.outDir)
+	return convertFile(ac, lg, opts, ac.input)
 }
 
-func pass3(pages []Page, uidBlock map[string]Child, referencedUID map[string]struct{}, outDir string) error {
-	bar := pb.StartNew(len(pages))
-	for _, page := range pages {
-		if page.Title == "" {
-			continue
-		}
-
-		title := strings.ReplaceAll(page.Title, "[[", "")
-		title = strings.ReplaceAll(title, "]]", "")
+// options returns the conversion options set by the flags and the config
+// file.
+func (ac appConfig) options(lg *logger) (convert.Options, error) {
+	fc, err := loadConfig(ac.config)
+	if err != nil {
+		return convert.Options{}, fmt.Errorf("load config: %w", err)
+	}
 
-		dest := filepath.Join(outDir, page.Title+".md")
-		if page.IsDaily {
-			dest = filepath.Join(outDir, "daily", page.Title+".md")
+	dailyPatterns := fc.DailyPatterns
+	for _, pattern := range ac.dailyPatterns {
+		p, err := roam.NewDailyPattern(pattern)
+		if err != nil {
+			return convert.Options{}, err
 		}
-
-		dir := filepath.Dir(dest)
-
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return err
+		dailyPatterns = append(dailyPatterns, p)
+	}
+	for _, name := range ac.dailyLocales {
+		l, ok := roam.LookupDateLocale(name)
+		if !ok {
+			return convert.Options{}, fmt.Errorf("unknown -daily-locale %q, want one of %s", name, strings.Join(roam.DateLocaleNames(), ", "))
 		}
-
-		lines, err := expandChildren(&page, uidBlock, referencedUID, 0)
+		p, err := l.DailyPattern()
 		if err != nil {
-			return err
+			return convert.Options{}, err
 		}
+		dailyPatterns = append(dailyPatterns, p)
+	}
 
-		data := strings.Join(lines, "\n")
-
-		if err := os.WriteFile(dest, []byte(data), 0644); err != nil {
-			return err
+	var exclude []*regexp.Regexp
+	for _, pattern := range append(fc.Exclude, ac.exclude...) {
+		re, err := convert.CompileExclude(pattern)
+		if err != nil {
+			return convert.Options{}, err
 		}
-
-		bar.Increment()
+		exclude = append(exclude, re)
 	}
-	bar.Finish()
-
-	return nil
-}
-
-func pass2(pages []Page, uidBlock map[string]Child, referencedUID map[string]struct{}) error {
-	fmt.Println("Pass 2: track blockrefs")
 
-	bar := pb.StartNew(len(pages))
-	for _, page := range pages {
-		_, err := expandChildren(&page, uidBlock, referencedUID, 0)
+	var include []*regexp.Regexp
+	for _, pattern := range append(fc.Include, ac.include...) {
+		re, err := convert.CompileExclude(pattern)
 		if err != nil {
-			return fmt.Errorf("pass2: %w", err)
+			return convert.Options{}, err
 		}
-		bar.Increment()
+		include = append(include, re)
 	}
-	bar.Finish()
-
-	return nil
-}
 
-func expandChildren(parent Parent, uidBlock map[string]Child, referencedUID map[string]struct{}, level int) ([]string, error) {
-	var lines []string
-
-	for _, child := range parent.Children() {
-		prefix := ""
-		if level > 0 {
-			prefix = strings.Repeat(" ", 4*level)
+	location := time.Local
+	if ac.tz != "" {
+		if location, err = time.LoadLocation(ac.tz); err != nil {
+			return convert.Options{}, fmt.Errorf("parse -tz: %w", err)
 		}
+	}
 
-		s := child.String
-		if child.Heading > 0 {
-			prefix = strings.Repeat("#", child.Heading) + " " + prefix
+	var dailyFrom, dailyTo time.Time
+	if ac.dailyFrom != "" {
+		if dailyFrom, err = time.Parse("2006-01-02", ac.dailyFrom); err != nil {
+			return convert.Options{}, fmt.Errorf("parse -daily-from: %w", err)
 		}
-
-		if len(child.Children()) > 0 && level > 0 {
-			prefix += "* "
+	}
+	if ac.dailyTo != "" {
+		if dailyTo, err = time.Parse("2006-01-02", ac.dailyTo); err != nil {
+			return convert.Options{}, fmt.Errorf("parse -daily-to: %w", err)
 		}
+	}
 
-		postfix := ""
-		if _, ok := referencedUID[child.UID]; ok {
-			postfix = fmt.Sprintf(" ^%s", child.UID)
+	widgets := map[string]string{}
+	for _, pair := range ac.widgets {
+		i := strings.Index(pair, "=")
+		if i < 0 {
+			return convert.Options{}, fmt.Errorf("parse -widgets: %q is not name=mode", pair)
 		}
+		widgets[strings.TrimSpace(pair[:i])] = strings.TrimSpace(pair[i+1:])
+	}
 
-		updated, err := replaceBlockRefs(s, uidBlock, referencedUID)
+	replacements := fc.Replace
+	if ac.rules != "" {
+		rules, err := convert.LoadRules(ac.rules)
 		if err != nil {
-			return nil, err
-		}
-
-		s = prefix + updated + postfix
-		if strings.ContainsRune(s, '\n') {
-			s = strings.ReplaceAll(s, "\n", "\n"+prefix) + "\n"
+			return convert.Options{}, fmt.Errorf("load rules: %w", err)
 		}
+		replacements = append(replacements, rules...)
+	}
 
-		lines = append(lines, s)
+	opts := convert.Options{
+		Logger:             lg,
+		Target:             ac.target,
+		AnnotationStyle:    ac.annotationStyle,
+		HiccupFallback:     ac.hiccupFallback,
+		LinkEmails:         ac.linkEmails,
+		RenderPolicy:       ac.renderPolicy,
+		DayStyle:           ac.dayStyle,
+		DailyPatterns:      dailyPatterns,
+		Disambiguate:       ac.disambiguate,
+		OnCollision:        ac.onCollision,
+		SkipEmpty:          ac.skipEmpty,
+		SkipOrphans:        ac.skipOrphans,
+		MaxFiles:           ac.maxFiles,
+		Shard:              ac.shard,
+		CodeWrappers:       ac.wrapperMode,
+		Style:              ac.style,
+		BulletMarker:       ac.bullet,
+		Indent:             ac.indent,
+		NestedHeadings:     ac.nestedHeadings,
+		TagsToEnd:          ac.tagsToEnd,
+		SlashTags:          ac.slashTags,
+		Quotes:             ac.quotes,
+		StripTitleEmoji:    ac.stripTitleEmoji,
+		Slug:               ac.slug,
+		Replacements:       replacements,
+		DryRun:             ac.dryRun,
+		FolderIndex:        ac.folderIndex,
+		CanvasFor:          ac.canvasFor,
+		Safe:               ac.safe,
+		Encrypted:          ac.encryptedBlocks,
+		Widgets:            widgets,
+		Queries:            ac.queries,
+		SmartBlocks:        ac.smartBlocks,
+		SRS:                ac.srs,
+		Tasks:              ac.tasks,
+		Iframes:            ac.iframes,
+		Location:           location,
+		EncryptionPassword: ac.encryptionPassword,
+		OnError:            ac.onError,
+		Strict:             ac.strict,
+		Resume:             ac.resume,
+		MobileSafe:         ac.mobileSafe,
+		KeepAliasBlocks:    ac.keepAliasBlocks,
+		PageTags:           ac.pageTags,
+		AuthorCallouts:     ac.authorCallouts,
+		AuthorFrontmatter:  ac.authorFrontmatter,
+		AuthorSuffix:       ac.authorSuffix,
+		MaxRefDepth:        ac.maxRefDepth,
+		TextAlign:          ac.textAlign,
+		Reactions:          ac.reactions,
+		Exclude:            exclude,
+		UnlinkExcluded:     ac.unlinkExcluded,
+		Include:            include,
+		IncludeLinked:      ac.includeLinked,
+		Pages:              ac.pages,
+		DailyFrom:          dailyFrom,
+		DailyTo:            dailyTo,
+		SkippedRefs:        ac.skippedRefs,
+		MissingRefs:        ac.missingRefs,
+		BareDates:          ac.bareDates,
+		DailyNotesConfig:   ac.dailyNotesConfig,
+		InitVault:          ac.initVault,
+		RoamCodePages:      ac.roamCodePages,
+		Templates:          ac.templates,
+		Anki:               ac.anki,
+		MOC:                ac.moc,
+		BacklinksSection:   ac.backlinksSection,
+		UIDMap:             ac.uidMap,
+	}
 
-		expanded, err := expandChildren(&child, uidBlock, referencedUID, level+1)
+	if ac.userMap != "" {
+		opts.UserMap, err = convert.LoadUserMap(ac.userMap)
 		if err != nil {
-			return nil, err
+			return convert.Options{}, fmt.Errorf("load user map: %w", err)
 		}
-
-		lines = append(lines, expanded...)
 	}
 
-	return lines, nil
-}
-
-func replaceBlockRefs(s string, uidBlock map[string]Child, referencedUID map[string]struct{}) (string, error) {
-	// need to replay block embeds, block mentions, block refs with some text
-
-	update := s
-
-	regexList := []*regexp.Regexp{reBlockEmbed, reBlockMentions, reBlockRef}
-
-	for {
-		var match []int
-		for _, re := range regexList {
-			match = re.FindStringSubmatchIndex(update)
-			if match == nil {
-				break
-			}
-
-			uid := update[match[4]:match[5]]
-			child, ok := uidBlock[uid]
-			if !ok {
-				fmt.Println("**** did not find uid:", uid)
-				continue
-			}
-
-			referencedUID[uid] = struct{}{}
-			head := update[:match[0]]
-			replacement := fmt.Sprintf("%s [[%s#^%s]]", child.String, child.Page.Title, child.UID)
-			tail := update[match[1]:]
-			update = head + replacement + tail
+	if ac.annotations != "" {
+		opts.Annotations, err = convert.LoadAnnotations(ac.annotations)
+		if err != nil {
+			return convert.Options{}, fmt.Errorf("load annotations: %w", err)
 		}
+	}
 
-		if match == nil {
-			break
-		}
+	if _, err := convert.New(opts); err != nil {
+		return convert.Options{}, fmt.Errorf("invalid config: %w", err)
 	}
 
-	return replaceDayLinks(update)
+	return opts, nil
 }
 
-func replaceDayLinks(in string) (string, error) {
-	update := in
+// convertFile converts the export at input into the output directory and
+// logs a summary.
+func convertFile(ac appConfig, lg *logger, opts convert.Options, input string) error {
+	return recordRun(ac, lg, opts, input, func(opts convert.Options) (convert.Stats, error) {
+		return convertPages(ac, lg, opts, input)
+	})
+}
 
-	for {
-		match := reDayLink.FindStringSubmatchIndex(update)
-		if match == nil {
-			break
+// recordRun runs a conversion of input with a progress bar and records it
+// in the history.
+func recordRun(ac appConfig, lg *logger, opts convert.Options, input string, convertFn func(convert.Options) (convert.Stats, error)) error {
+	bar := ac.progressBar()
+	opts.Progress = bar
+
+	started := time.Now()
+	st, err := convertFn(opts)
+	// a dry run writes no files, the history included
+	if !ac.noHistory && !opts.DryRun {
+		entry := historyEntry{
+			Time:       started,
+			Input:      input,
+			Output:     ac.output(),
+			Options:    setFlags(),
+			DurationMS: time.Since(started).Milliseconds(),
+			PhaseMS:    map[string]int64{},
+			Stats:      st,
+		}
+		for phase, d := range bar.phases {
+			entry.PhaseMS[phase] = d.Milliseconds()
 		}
-
-		date := update[match[4]:match[5]]
-		obsDate, _, err := parseRoamDate(date)
 		if err != nil {
-			return "", fmt.Errorf("invalid date %q: %w", date, err)
+			entry.Error = err.Error()
 		}
 
-		head := update[:match[0]] + "[["
-		tail := "]]" + update[match[1]:]
-		update = head + obsDate + tail
+		if herr := ac.recordHistory(entry); herr != nil {
+			lg.Warn("record history", "error", herr.Error())
+		}
 	}
 
-	return update, nil
+	return err
 }
 
-func pass1(pages []Page) (map[string]Child, error) {
-	fmt.Println("Pass 1: scan all pages")
-	bar := pb.StartNew(len(pages))
+func convertPages(ac appConfig, lg *logger, opts convert.Options, input string) (convert.Stats, error) {
+	pages, err := ac.loadPages(input)
+	if err != nil {
+		return convert.Stats{}, fmt.Errorf("load input: %w", err)
+	}
 
-	uidBlock := map[string]Child{}
+	return writePages(ac, lg, opts, input, pages)
+}
 
-	for i, page := range pages {
-		title, err := parsePageDate(&page)
-		if err != nil {
-			return nil, fmt.Errorf("parse page date: %w", err)
-		}
-		pages[i].Title = title
+// writePages converts the pages loaded from input into the output vault.
+func writePages(ac appConfig, lg *logger, opts convert.Options, input string, pages []roam.Page) (convert.Stats, error) {
+	c, err := convert.New(opts)
+	if err != nil {
+		return convert.Stats{}, fmt.Errorf("invalid config: %w", err)
+	}
 
-		// collect uid
-		collectBlocks(uidBlock, &page, page.RawChildren)
+	lg.Info("converting", "input", input, "pages", len(pages), "output", ac.output())
 
-		bar.Increment()
+	w, closeVault, err := ac.vaultWriter()
+	if err != nil {
+		return convert.Stats{}, err
 	}
 
-	bar.Finish()
-
-	return uidBlock, nil
-}
+	if err := c.Convert(pages, w); err != nil {
+		return c.Stats(), err
+	}
 
-func collectBlocks(uidList map[string]Child, page *Page, children []Child) {
-	for _, child := range children {
-		child.Page = *page
-		uidList[child.UID] = child
-		collectBlocks(uidList, page, child.RawChildren)
+	if !opts.DryRun {
+		if err := closeVault(); err != nil {
+			return c.Stats(), fmt.Errorf("write vault: %w", err)
+		}
 	}
-}
 
-func parsePageDate(page *Page) (string, error) {
-	update, ok, err := parseRoamDate(page.Title)
-	if err != nil {
-		return "", err
+	st := c.Stats()
+	lg.Info("summary",
+		"pages", st.Pages,
+		"daily_notes", st.DailyNotes,
+		"skipped", st.Skipped,
+		"quarantined", st.Quarantined,
+		"blocks", st.Blocks,
+		"block_refs_resolved", st.BlockRefsResolved,
+		"block_refs_unresolved", st.BlockRefsUnresolved,
+		"tags", st.Tags,
+		"assets", st.Assets,
+		"warnings", st.Warnings,
+	)
+
+	if ac.stats != "" && !opts.DryRun {
+		data, err := json.MarshalIndent(st, "", "  ")
+		if err != nil {
+			return st, err
+		}
+		if err := os.WriteFile(ac.stats, append(data, '\n'), 0644); err != nil {
+			return st, fmt.Errorf("write stats: %w", err)
+		}
 	}
 
-	if ok {
-		page.IsDaily = true
+	return st, nil
+}
+
+// loadPages reads the pages of input, pulling them with the Roam API when
+// -graph is given.
+func (ac *appConfig) loadPages(input string) ([]roam.Page, error) {
+	if graph := strings.TrimPrefix(input, roamAPIInput); graph != input {
+		return roam.NewAPIClient(graph, ac.token).Pages()
 	}
 
-	return update, nil
+	return roam.Importers.Import(input, ac.format)
 }
 
-func parseRoamDate(in string) (string, bool, error) {
-	match := reDaily.FindAllStringSubmatch(in, -1)
+// vaultWriter returns the writer for the output vault, a directory or a zip
+// file, encrypting the files when -encrypt is given. The returned func
+// finishes the vault once every file is written.
+func (ac *appConfig) vaultWriter() (vault.Writer, func() error, error) {
+	var w vault.Writer = vault.NewDir(ac.outDir)
+	finish := func() error { return nil }
+	if ac.outZip != "" {
+		z := vault.NewZip(ac.outZip)
+		w, finish = z, z.Close
+	}
 
-	if len(match) != 1 {
-		return in, false, nil
+	if len(ac.encrypt) == 0 {
+		return w, finish, nil
 	}
-	row := match[0]
-	rawTitle := fmt.Sprintf("%s %s %s", row[1], row[2], row[3])
 
-	t, err := time.Parse(roamDailyLayout, rawTitle)
-	if err != nil {
-		return "", false, err
+	var recipients []age.Recipient
+	for _, spec := range ac.encrypt {
+		r, err := vault.ParseRecipient(spec)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid -encrypt: %w", err)
+		}
+		recipients = append(recipients, r)
 	}
 
-	return t.Format(obsDailyLayout), true, nil
+	return vault.NewEncrypted(w, recipients...), finish, nil
 }
 
-func loadJSON(jsonPath string) ([]Page, error) {
-	f, err := os.Open(jsonPath)
-	if err != nil {
-		return nil, err
+// output returns where the vault is written.
+func (ac *appConfig) output() string {
+	if ac.outZip != "" {
+		return ac.outZip
 	}
 
-	defer func(f *os.File) {
-		err := f.Close()
-		if err != nil {
+	return ac.outDir
+}
 
+// recordHistory appends entry to the history file.
+func (ac *appConfig) recordHistory(entry historyEntry) error {
+	historyPath := ac.historyFile
+	if historyPath == "" {
+		var err error
+		if historyPath, err = defaultHistoryPath(); err != nil {
+			return err
 		}
-	}(f)
-
-	var pages []Page
-
-	if err := json.NewDecoder(f).Decode(&pages); err != nil {
-		return nil, err
 	}
 
-	return pages, nil
+	return appendHistory(historyPath, entry)
 }
 
 type appConfig struct {
 	input  string
+	format string
 	outDir string
+	outZip string
+	config string
+
+	// graph and token read the input with the Roam API.
+	graph string
+	token string
+
+	// preview prints the notes of the -page pages instead of writing them.
+	preview bool
+	// tui browses and previews the export before converting it.
+	tui bool
+
+	watch         string
+	watchInterval time.Duration
+
+	target string
+
+	annotations     string
+	annotationStyle string
+
+	hiccupFallback string
+
+	userMap    string
+	linkEmails bool
+
+	renderPolicy string
+
+	safe               bool
+	encryptedBlocks    string
+	widgets            stringList
+	queries            string
+	smartBlocks        string
+	srs                string
+	tasks              string
+	iframes            string
+	tz                 string
+	encryptionPassword string
+	onError            string
+	strict             bool
+	resume             bool
+	mobileSafe         bool
+	authorFrontmatter  bool
+	authorSuffix       bool
+
+	keepAliasBlocks  bool
+	pageTags         string
+	authorCallouts   bool
+	maxRefDepth      int
+	textAlign        string
+	reactions        string
+	exclude          repeatedFlag
+	unlinkExcluded   bool
+	include          repeatedFlag
+	includeLinked    bool
+	pages            repeatedFlag
+	dailyFrom        string
+	dailyTo          string
+	skippedRefs      string
+	missingRefs      string
+	bareDates        string
+	dailyNotesConfig bool
+	initVault        bool
+	roamCodePages    bool
+	templates        bool
+	anki             bool
+	moc              string
+	backlinksSection bool
+	uidMap           bool
+
+	disambiguate bool
+	onCollision  string
+
+	dayStyle string
+
+	skipEmpty   bool
+	skipOrphans bool
+
+	maxFiles int
+	shard    stringList
+
+	wrapperMode string
+
+	dailyPatterns repeatedFlag
+	dailyLocales  stringList
+
+	style          string
+	bullet         string
+	indent         string
+	nestedHeadings string
+	tagsToEnd      bool
+	slashTags      string
+	quotes         string
+
+	stripTitleEmoji bool
+	slug            bool
+
+	rules  string
+	dryRun bool
+
+	stats       string
+	folderIndex string
+	canvasFor   repeatedFlag
+
+	historyFile string
+	noHistory   bool
+
+	encrypt repeatedFlag
+
+	verbose     bool
+	veryVerbose bool
+	quiet       bool
+	logFormat   string
+	progress    string
+	logPage     string
 }
 
-func (ac *appConfig) Validate() error {
-	if ac.input == "" {
-		return errors.New("input is blank")
-	}
-
-	if ac.outDir == "" {
-		wd, err := os.Getwd()
+// logger returns the logger selected by the verbosity and log flags.
+func (ac *appConfig) logger() (*logger, error) {
+	var page *regexp.Regexp
+	if ac.logPage != "" {
+		re, err := regexp.Compile(ac.logPage)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("invalid -log-page: %w", err)
 		}
-		ac.outDir = wd
+		page = re
 	}
 
-	return nil
-}
-
-type Parent interface {
-	Children() []Child
-}
-
-type Page struct {
-	Title         string  `json:"title"`
-	RawChildren   []Child `json:"children"`
-	RawCreateTime int     `json:"create-time"`
-	CreateEmail   string  `json:"create-email"`
-	RawEditTime   int     `json:"edit-time"`
-	EditEmail     string  `json:"edit-email"`
-
-	CreateTime time.Time `json:"-"`
-	EditTime   time.Time `json:"-"`
+	level := levelInfo
+	switch {
+	case ac.quiet:
+		level = levelError
+	case ac.veryVerbose:
+		level = levelTrace
+	case ac.verbose:
+		level = levelDebug
+	}
 
-	IsDaily bool `json:"-"`
-}
+	switch ac.logFormat {
+	case logFormatText, logFormatJSON:
+	default:
+		return nil, fmt.Errorf("unknown log format %q", ac.logFormat)
+	}
 
-func (p *Page) Children() []Child {
-	return p.RawChildren
+	return newLogger(os.Stderr, level, ac.logFormat == logFormatJSON, page), nil
 }
 
-var _ json.Unmarshaler = &Page{}
-var _ Parent = &Page{}
+// progressBar returns the progress display for a conversion. -quiet hides
+// the bars but not JSON progress, which was asked for explicitly.
+func (ac *appConfig) progressBar() *progressBar {
+	mode := ac.progress
+	if ac.quiet && mode == progressModeBar {
+		mode = progressModeNone
+	}
 
-type dummyPage Page
+	return newProgressBar(mode, os.Stderr)
+}
 
-func (p *Page) UnmarshalJSON(bytes []byte) error {
-	d := &dummyPage{}
+const (
+	// roamTokenEnv holds the Roam API token when -token isn't given.
+	roamTokenEnv = "ROAM_API_TOKEN"
+	// roamAPIInput starts the input of a conversion reading a graph with
+	// the Roam API, such as roam-api:mygraph.
+	roamAPIInput = "roam-api:"
+)
 
-	if err := json.Unmarshal(bytes, d); err != nil {
-		return err
+func (ac *appConfig) Validate() error {
+	if ac.graph != "" {
+		if ac.input != "" || ac.watch != "" {
+			return errors.New("-graph can't be combined with -i or -watch")
+		}
+		if ac.token == "" {
+			ac.token = os.Getenv(roamTokenEnv)
+		}
+		if ac.token == "" {
+			return fmt.Errorf("-graph needs -token or $%s", roamTokenEnv)
+		}
+		ac.input = roamAPIInput + ac.graph
 	}
 
-	p.Title = d.Title
-	p.RawChildren = d.RawChildren
-	p.CreateEmail = d.CreateEmail
-	p.EditEmail = d.EditEmail
+	if ac.input == "" && ac.watch == "" {
+		return errors.New("input is blank")
+	}
 
-	if p.RawCreateTime == 0 {
-		p.RawCreateTime = int(time.Now().Unix())
+	switch ac.progress {
+	case progressModeBar, progressModeJSON, progressModeNone:
+	default:
+		return fmt.Errorf("unknown progress mode %q", ac.progress)
 	}
 
-	if p.RawEditTime == 0 {
-		p.RawEditTime = int(time.Now().Unix())
+	if ac.outZip != "" {
+		if ac.outDir != "" {
+			return errors.New("-d and -o can't be combined")
+		}
+		if !strings.EqualFold(filepath.Ext(ac.outZip), ".zip") {
+			return fmt.Errorf("-o must name a .zip file, got %q", ac.outZip)
+		}
 	}
 
-	p.CreateTime = time.Unix(int64(d.RawCreateTime), 0)
-	p.EditTime = time.Unix(int64(d.RawEditTime), 0)
+	if ac.outDir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		ac.outDir = wd
+	}
 
 	return nil
 }
 
-type Child struct {
-	UID           string  `json:"uid"`
-	String        string  `json:"string"`
-	RawChildren   []Child `json:"children"`
-	RawCreateTime int     `json:"create-time"`
-	CreateEmail   string  `json:"create-email"`
-	RawEditTime   int     `json:"edit-time"`
-	EditEmail     string  `json:"edit-email"`
-	Heading       int     `json:"heading"`
-	Emojis        []Emoji `json:"emojis"`
-	TextAlign     string  `json:"text-align"`
-
-	CreateTime time.Time `json:"-"`
-	EditTime   time.Time `json:"-"`
-
-	Page Page `json:"-"`
-}
-
-var _ json.Unmarshaler = &Child{}
-var _ Parent = &Child{}
+// stringList is a flag holding a comma separated list. It can be repeated.
+type stringList []string
 
-type dummyChild Child
-
-func (c *Child) Children() []Child {
-	return c.RawChildren
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
 }
 
-func (c *Child) UnmarshalJSON(bytes []byte) error {
-	d := &dummyChild{}
-
-	if err := json.Unmarshal(bytes, d); err != nil {
-		return err
-	}
-	c.UID = d.UID
-	c.String = d.String
-	c.RawChildren = d.RawChildren
-	c.CreateEmail = d.CreateEmail
-	c.EditEmail = d.EditEmail
-	c.Heading = d.Heading
-	c.Emojis = d.Emojis
-	c.TextAlign = d.TextAlign
-
-	if c.RawCreateTime == 0 {
-		c.RawCreateTime = int(time.Now().Unix())
-	}
-
-	if c.RawEditTime == 0 {
-		c.RawEditTime = int(time.Now().Unix())
+func (l *stringList) Set(s string) error {
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
 	}
 
-	c.CreateTime = time.Unix(int64(d.RawCreateTime), 0)
-	c.EditTime = time.Unix(int64(d.RawEditTime), 0)
-
 	return nil
 }
 
-type Emoji struct {
-	Emoji map[string]interface{}   `json:"emoji"`
-	Users []map[string]interface{} `json:"users"`
-}
+// repeatedFlag is a flag that collects every value it is given.
+type repeatedFlag []string
 
-var (
-	reDaily         = regexp.MustCompile(`^(January|February|March|April|May|June|July|August|September|October|November|December) ([0-9]+)[a-z]{2}, ([0-9]{4})$`)
-	reDayLink       = regexp.MustCompile(`(\[\[)([January|February|March|April|May|June|July|August|September|October|November|December [0-9]+[a-z]{2}, [0-9]{4})(\]\])`)
-	reBlockEmbed    = regexp.MustCompile(`({{embed: \(\()(.{9})(\)\)}})`)
-	reBlockMentions = regexp.MustCompile(`({{mentions: \(\()(.{9})(\)\)}})`)
-	reBlockRef      = regexp.MustCompile(`(\(\()(.{9})(\)\))`)
-)
+func (f *repeatedFlag) String() string {
+	return strings.Join(*f, " ")
+}
 
-const (
-	roamDailyLayout = "January _2 2006"
-	obsDailyLayout  = "2006-01-02"
-)
+func (f *repeatedFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
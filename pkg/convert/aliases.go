@@ -0,0 +1,56 @@
+package convert
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// collectAliases adds the values of a page's top-level alias:: blocks to its
+// aliases. Unless keep is set, alias blocks without children are removed.
+func (c *Converter) collectAliases(page *roam.Page, keep bool) {
+	children := page.RawChildren[:0:0]
+
+	for _, child := range page.RawChildren {
+		m := reAliasBlock.FindStringSubmatch(child.String)
+		if m == nil {
+			children = append(children, child)
+			continue
+		}
+
+		for _, alias := range parseAliases(m[1]) {
+			if alias != page.Title && !containsString(c.aliases[page.Title], alias) {
+				c.aliases[page.Title] = append(c.aliases[page.Title], alias)
+			}
+		}
+
+		if keep || len(child.Children()) > 0 {
+			children = append(children, child)
+		}
+	}
+
+	page.RawChildren = children
+}
+
+// parseAliases splits the value of an alias:: attribute. Values are either
+// page links or a comma separated list.
+func parseAliases(s string) []string {
+	var aliases []string
+	if links := reWikiLink.FindAllStringSubmatch(s, -1); len(links) > 0 {
+		for _, link := range links {
+			aliases = append(aliases, strings.TrimSpace(link[1]))
+		}
+		return aliases
+	}
+
+	for _, alias := range strings.Split(s, ",") {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	return aliases
+}
+
+var reAliasBlock = regexp.MustCompile(`(?i)^\s*alias::\s*([^\n]*)$`)
@@ -0,0 +1,29 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// AlignStrip drops the alignment of blocks.
+	AlignStrip = "strip"
+	// AlignHTML wraps aligned blocks in a div that sets text-align.
+	AlignHTML = "html"
+)
+
+// align applies the alignment policy to s, the text of a block aligned to
+// textAlign. Left alignment is the default and is never written.
+func (c *Converter) align(s, textAlign string) string {
+	textAlign = strings.ToLower(textAlign)
+	if c.opts.TextAlign != AlignHTML || s == "" {
+		return s
+	}
+
+	switch textAlign {
+	case "center", "right", "justify":
+		return fmt.Sprintf(`<div style="text-align:%s">%s</div>`, textAlign, s)
+	default:
+		return s
+	}
+}
@@ -0,0 +1,144 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	// ankiFile is the Anki import file written next to the notes.
+	ankiFile = "flashcards.tsv"
+
+	// ankiHeader tells Anki how to read the file: tab separated HTML
+	// fields with column names.
+	ankiHeader = "#separator:tab\n#html:true\n#columns:Front\tBack\tSource\n"
+)
+
+// ankiTags mark the blocks that are flashcards.
+var ankiTags = []string{"anki", "flashcard"}
+
+// ankiCard is a flashcard: a block's text, its children and a link back to
+// the block.
+type ankiCard struct {
+	front, back, source string
+}
+
+// collectAnkiBlocks finds the blocks tagged as flashcards and marks them as
+// referenced, so the cards can link to them.
+func (c *Converter) collectAnkiBlocks(pages []roam.Page) {
+	var walk func(children []roam.Child)
+	walk = func(children []roam.Child) {
+		for _, child := range children {
+			if hasAnkiTag(child.String) {
+				c.ankiUIDs[child.UID] = struct{}{}
+				c.referencedUID[child.UID] = struct{}{}
+			}
+			walk(child.RawChildren)
+		}
+	}
+
+	for i := range pages {
+		walk(pages[i].RawChildren)
+	}
+}
+
+func hasAnkiTag(s string) bool {
+	for _, m := range reTagToken.FindAllStringSubmatch(s, -1) {
+		if isAnkiTag(m[2]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isAnkiTag(tag string) bool {
+	name := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(tag, "#"), "[["), "]]")
+	for _, t := range ankiTags {
+		if strings.EqualFold(name, t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addAnkiCard records a card for block, a flashcard whose converted text is
+// text. Its children, the answer, are expanded without anchors.
+func (c *Converter) addAnkiCard(block *roam.Child, text string) error {
+	front := reTagToken.ReplaceAllStringFunc(text, func(tag string) string {
+		if isAnkiTag(strings.TrimSpace(tag)) {
+			return ""
+		}
+		return tag
+	})
+	front = strings.TrimSpace(reSpaceRun.ReplaceAllString(front, " "))
+
+	c.embedding[block.UID] = struct{}{}
+	writing := c.writing
+	c.writing = false
+	lines, err := c.expandChildren(block, 0)
+	c.writing = writing
+	delete(c.embedding, block.UID)
+	if err != nil {
+		return err
+	}
+
+	c.ankiCards = append(c.ankiCards, ankiCard{
+		front:  front,
+		back:   strings.Join(lines, "\n"),
+		source: c.rewriteLinks(fmt.Sprintf("[[%s#^%s]]", c.page.Title, block.UID)),
+	})
+
+	return nil
+}
+
+// writeAnkiCards writes the flashcards to a file Anki can import, with the
+// Markdown of each side rendered as HTML.
+func (c *Converter) writeAnkiCards() error {
+	if len(c.ankiCards) == 0 {
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(ankiHeader)
+	for _, card := range c.ankiCards {
+		front, err := ankiField(card.front)
+		if err != nil {
+			return err
+		}
+		back, err := ankiField(card.back)
+		if err != nil {
+			return err
+		}
+
+		sb.WriteString(front + "\t" + back + "\t" + ankiText(card.source) + "\n")
+	}
+
+	c.log.Debug("write flashcards", "path", ankiFile, "cards", len(c.ankiCards))
+
+	if err := c.w.WriteFile(ankiFile, []byte(sb.String())); err != nil {
+		return err
+	}
+	c.stats.Assets++
+
+	return nil
+}
+
+// ankiField renders Markdown as HTML on a single line.
+func ankiField(md string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(md), &buf); err != nil {
+		return "", err
+	}
+
+	return ankiText(strings.TrimSpace(buf.String())), nil
+}
+
+// ankiText keeps s on one field of one line.
+func ankiText(s string) string {
+	return strings.NewReplacer("\t", " ", "\r", "", "\n", " ").Replace(s)
+}
@@ -0,0 +1,137 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	AnnotationFootnote = "footnote"
+	AnnotationCallout  = "callout"
+)
+
+// Annotation is a note attached to a block from an outside source, such as a
+// hypothes.is export or a team comment tool.
+type Annotation struct {
+	UID     string `json:"uid"`
+	Author  string `json:"author"`
+	Text    string `json:"text"`
+	Source  string `json:"source"`
+	Created string `json:"created"`
+}
+
+// LoadAnnotations reads an annotations file. The file is either a JSON object
+// mapping block UIDs to lists of annotations, or a JSON array of annotations
+// that each carry their block UID.
+func LoadAnnotations(annotationsPath string) (map[string][]Annotation, error) {
+	data, err := os.ReadFile(annotationsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	byUID := map[string][]Annotation{}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		var list []Annotation
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, err
+		}
+
+		for _, a := range list {
+			if a.UID == "" {
+				return nil, fmt.Errorf("annotation %q has no uid", a.Text)
+			}
+			byUID[a.UID] = append(byUID[a.UID], a)
+		}
+
+		return byUID, nil
+	}
+
+	if err := json.Unmarshal(data, &byUID); err != nil {
+		return nil, err
+	}
+
+	for uid := range byUID {
+		for i := range byUID[uid] {
+			byUID[uid][i].UID = uid
+		}
+	}
+
+	return byUID, nil
+}
+
+// annotationRefs returns the footnote references for a block's annotations.
+func (c *Converter) annotationRefs(uid string) string {
+	var sb strings.Builder
+	for i := range c.opts.Annotations[uid] {
+		fmt.Fprintf(&sb, " [^%s]", annotationLabel(uid, i))
+	}
+
+	return sb.String()
+}
+
+// annotationFootnotes returns the footnote definitions for every annotated
+// block below parent, in document order.
+func (c *Converter) annotationFootnotes(parent roam.Parent) []string {
+	var lines []string
+
+	for _, child := range parent.Children() {
+		for i, a := range c.opts.Annotations[child.UID] {
+			text := strings.ReplaceAll(a.Text, "\n", " ")
+			lines = append(lines, fmt.Sprintf("[^%s]: %s%s", annotationLabel(child.UID, i), text, a.attribution()))
+		}
+
+		lines = append(lines, c.annotationFootnotes(&child)...)
+	}
+
+	return lines
+}
+
+// annotationCallouts renders a block's annotations as callouts indented to
+// line up with the block.
+func (c *Converter) annotationCallouts(uid, indent string) []string {
+	var lines []string
+
+	for _, a := range c.opts.Annotations[uid] {
+		title := "Annotation"
+		if a.Author != "" {
+			title = a.Author
+		}
+
+		lines = append(lines, fmt.Sprintf("%s> [!note] %s", indent, title))
+		for _, line := range strings.Split(a.Text, "\n") {
+			lines = append(lines, fmt.Sprintf("%s> %s", indent, line))
+		}
+
+		if a.Source != "" {
+			lines = append(lines, fmt.Sprintf("%s> — %s", indent, a.Source))
+		}
+	}
+
+	return lines
+}
+
+func (a Annotation) attribution() string {
+	var parts []string
+	if a.Author != "" {
+		parts = append(parts, a.Author)
+	}
+	if a.Source != "" {
+		parts = append(parts, a.Source)
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+
+	return " — " + strings.Join(parts, ", ")
+}
+
+func annotationLabel(uid string, i int) string {
+	return fmt.Sprintf("%s-%d", uid, i+1)
+}
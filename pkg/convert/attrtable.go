@@ -0,0 +1,136 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// attrIndex is what {{attr-table}} needs to know about the graph: the pages
+// that link to each page and the attributes used on each page.
+type attrIndex struct {
+	referrers map[string]map[string]struct{}
+	attrs     map[string]map[string]struct{}
+}
+
+// attrTable writes {{attr-table: [[Page]] sort}} as a Dataview table of the
+// pages linking to Page, with a column for every attribute they use. Only
+// Obsidian vaults have Dataview.
+func (c *Converter) attrTable(call componentCall) (string, bool) {
+	if c.opts.Target != TargetObsidian {
+		return "", false
+	}
+
+	m := reAttrTableArgs.FindStringSubmatch(call.Args)
+	if m == nil {
+		return "", false
+	}
+	title, sortBy := m[1], strings.TrimSpace(m[2])
+
+	index := c.attrIndex()
+	attrs := map[string]struct{}{}
+	for page := range index.referrers[title] {
+		for attr := range index.attrs[page] {
+			attrs[attr] = struct{}{}
+		}
+	}
+
+	names := make([]string, 0, len(attrs))
+	for attr := range attrs {
+		names = append(names, attr)
+	}
+	sort.Strings(names)
+
+	columns := []string{`file.link AS "Page"`}
+	for _, name := range names {
+		columns = append(columns, fmt.Sprintf("%s AS %q", dataviewField(name), name))
+	}
+
+	lines := []string{
+		"```dataview",
+		"TABLE WITHOUT ID " + strings.Join(columns, ", "),
+		"FROM " + c.queryLink(title),
+	}
+	if sortBy != "" {
+		lines = append(lines, "SORT "+dataviewField(strings.Trim(sortBy, "[]")))
+	}
+	lines = append(lines, "```")
+
+	return strings.Join(lines, "\n"), true
+}
+
+// attrIndex scans the graph the first time an attribute table needs it.
+func (c *Converter) attrIndex() *attrIndex {
+	if c.attrs != nil {
+		return c.attrs
+	}
+
+	c.attrs = &attrIndex{
+		referrers: map[string]map[string]struct{}{},
+		attrs:     map[string]map[string]struct{}{},
+	}
+	for _, block := range c.uidBlock {
+		page := block.Page.Title
+
+		for _, target := range linkTargets(block.String) {
+			if target == page {
+				continue
+			}
+			if c.attrs.referrers[target] == nil {
+				c.attrs.referrers[target] = map[string]struct{}{}
+			}
+			c.attrs.referrers[target][page] = struct{}{}
+		}
+
+		if m := reAttribute.FindStringSubmatch(block.String); m != nil {
+			if c.attrs.attrs[page] == nil {
+				c.attrs.attrs[page] = map[string]struct{}{}
+			}
+			c.attrs.attrs[page][strings.TrimSpace(m[1])] = struct{}{}
+		}
+	}
+
+	return c.attrs
+}
+
+// queryLink returns the link a Dataview query uses for title, following
+// converted daily note titles and renamed pages.
+func (c *Converter) queryLink(title string) string {
+	if t, ok, err := c.parseDailyTitle(title); ok && err == nil {
+		title = c.formatDaily(t)
+	}
+
+	link := c.rewriteLinks("[[" + title + "]]")
+	if i := strings.Index(link, "|"); i >= 0 {
+		link = link[:i] + "]]"
+	}
+
+	return link
+}
+
+// dataviewField returns the name Dataview gives an inline field: lower case,
+// with formatting removed and spaces turned into dashes.
+func dataviewField(name string) string {
+	name = strings.Trim(strings.TrimSpace(name), "*_`")
+
+	var sb strings.Builder
+	space := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			if space && sb.Len() > 0 {
+				sb.WriteRune('-')
+			}
+			space = false
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String()
+}
+
+var reAttrTableArgs = regexp.MustCompile(`^\[\[([^\[\]]+)\]\]\s*(.*)$`)
@@ -0,0 +1,98 @@
+package convert
+
+import (
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// expandPage renders the blocks of page. With author callouts, runs of
+// top-level blocks created by someone other than the page's owner are
+// wrapped in a callout naming the author.
+func (c *Converter) expandPage(page *roam.Page) ([]string, error) {
+	if !c.opts.AuthorCallouts {
+		return c.expandChildren(page, 0)
+	}
+
+	owner := pageOwner(page)
+	author := func(child roam.Child) string {
+		if child.Creator() == "" || child.Creator() == owner {
+			return ""
+		}
+		return child.Creator()
+	}
+
+	var lines []string
+	children := page.RawChildren
+	for len(children) > 0 {
+		user := author(children[0])
+		n := 1
+		for n < len(children) && author(children[n]) == user {
+			n++
+		}
+
+		expanded, err := c.expandChildren(&roam.Page{RawChildren: children[:n]}, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case user == "":
+			lines = append(lines, expanded...)
+		case len(lines) == 0 || lines[len(lines)-1] == "":
+			lines = append(lines, authorCallout(c.contactName(user), expanded)[1:]...)
+		default:
+			lines = append(lines, authorCallout(c.contactName(user), expanded)...)
+		}
+
+		children = children[n:]
+	}
+
+	return lines, nil
+}
+
+// authorCallout quotes lines in a callout attributed to name. Blank lines
+// keep the callout apart from the blocks around it.
+func authorCallout(name string, lines []string) []string {
+	out := []string{"", "> [!quote] " + name}
+	for _, line := range lines {
+		for _, l := range strings.Split(strings.TrimSuffix(line, "\n"), "\n") {
+			if l == "" {
+				out = append(out, ">")
+				continue
+			}
+			out = append(out, "> "+l)
+		}
+	}
+
+	return append(out, "")
+}
+
+// pageOwner returns the user who created page, falling back to the creator
+// of its first block.
+func pageOwner(page *roam.Page) string {
+	if page.Creator() == "" && len(page.RawChildren) > 0 {
+		return page.RawChildren[0].Creator()
+	}
+
+	return page.Creator()
+}
+
+// pageAuthor returns the display name of the owner of page.
+func (c *Converter) pageAuthor(page *roam.Page) string {
+	if owner := pageOwner(page); owner != "" {
+		return c.contactName(owner)
+	}
+
+	return ""
+}
+
+// authorSuffix names the author of a block created by someone other than
+// the owner of the page being written. Nothing follows closing fences.
+func (c *Converter) authorSuffix(child *roam.Child, text string) string {
+	if !c.opts.AuthorSuffix || child.Creator() == "" || child.Creator() == pageOwner(c.page) || endsWithFence(text) {
+		return ""
+	}
+
+	return " — " + c.contactName(child.Creator())
+}
@@ -0,0 +1,92 @@
+package convert
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const backlinkExcerpt = 100
+
+// backlink is a block that links to a page.
+type backlink struct {
+	page    string
+	excerpt string
+}
+
+// collectBacklinks records, for every page, the blocks on other converted
+// pages that link to it or reference one of its blocks.
+func (c *Converter) collectBacklinks(pages []roam.Page) {
+	var walk func(page *roam.Page, children []roam.Child)
+	walk = func(page *roam.Page, children []roam.Child) {
+		for _, child := range children {
+			seen := map[string]struct{}{}
+			for _, target := range c.pageLinks(&roam.Page{RawChildren: []roam.Child{{String: child.String}}}) {
+				if _, ok := seen[target]; ok || target == page.Title {
+					continue
+				}
+				seen[target] = struct{}{}
+				c.backlinks[target] = append(c.backlinks[target], backlink{page: page.Title, excerpt: excerpt(child.String)})
+			}
+
+			walk(page, child.RawChildren)
+		}
+	}
+
+	for i := range pages {
+		if _, ok := c.quarantined[i]; ok {
+			continue
+		}
+		if _, ok := c.skipped[i]; ok {
+			continue
+		}
+		walk(&pages[i], pages[i].RawChildren)
+	}
+}
+
+// backlinksSection lists the blocks that link to page, grouped by the page
+// they are on.
+func (c *Converter) backlinksSection(page *roam.Page) []string {
+	links := c.backlinks[page.Title]
+	if len(links) == 0 {
+		return nil
+	}
+
+	lines := []string{"", "## Backlinks", ""}
+	last := ""
+	for _, link := range links {
+		if link.page != last {
+			lines = append(lines, "- "+c.rewriteLinks("[["+link.page+"]]"))
+			last = link.page
+		}
+		lines = append(lines, "\t- "+link.excerpt)
+	}
+
+	return lines
+}
+
+// excerpt shortens the text of a block to one line without links or tags,
+// so the backlinks section doesn't add links of its own.
+func excerpt(s string) string {
+	s = reTagToken.ReplaceAllStringFunc(s, func(token string) string {
+		m := reTagToken.FindStringSubmatch(token)
+		return m[1] + strings.TrimPrefix(m[2], "#")
+	})
+	s = reWikiLink.ReplaceAllStringFunc(s, func(link string) string {
+		inner := link[2 : len(link)-2]
+		if i := strings.Index(inner, "|"); i >= 0 {
+			return inner[i+1:]
+		}
+		return inner
+	})
+	s = strings.Join(strings.Fields(s), " ")
+
+	if utf8.RuneCountInString(s) <= backlinkExcerpt {
+		return s
+	}
+
+	runes := []rune(s)
+
+	return strings.TrimSpace(string(runes[:backlinkExcerpt])) + "…"
+}
@@ -0,0 +1,539 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// Block layouts.
+const (
+	layoutIndent = iota
+	layoutOutline
+	layoutParagraph
+)
+
+// blockLayout returns how blocks at level are laid out, and for outlines the
+// depth of the list item. Logseq and HTML output are always outlines so the
+// structure of the page survives.
+func (c *Converter) blockLayout(level int) (int, int) {
+	switch {
+	case c.opts.Target != TargetObsidian || c.opts.Style == StyleOutline:
+		return layoutOutline, level
+	case c.opts.Style == StyleProse && level == 0:
+		return layoutParagraph, 0
+	case c.opts.Style == StyleProse:
+		return layoutOutline, level - 1
+	default:
+		return layoutIndent, level
+	}
+}
+
+// indentUnit returns what a level of nesting is indented by, which is def
+// unless Options.Indent sets it.
+func (c *Converter) indentUnit(def string) string {
+	switch c.opts.Indent {
+	case "":
+		return def
+	case IndentTab:
+		return "\t"
+	}
+
+	n, _ := strconv.Atoi(c.opts.Indent)
+	return strings.Repeat(" ", n)
+}
+
+// outline reports whether every block is written as a list item.
+func (c *Converter) outline() bool {
+	layout, _ := c.blockLayout(1)
+	return layout == layoutOutline
+}
+
+func (c *Converter) expandChildren(parent roam.Parent, level int) ([]string, error) {
+	var lines []string
+
+	numbered := c.opts.BulletMarker == BulletNumbered
+	if p, ok := parent.(*roam.Child); ok && p.ViewType == roam.ViewNumbered {
+		numbered = true
+	}
+
+	layout, depth := c.blockLayout(level)
+
+	for i, child := range parent.Children() {
+		prefix := ""
+		if layout == layoutIndent && level > 0 {
+			prefix = strings.Repeat(c.indentUnit("    "), level)
+		}
+		indent := prefix
+
+		if c.writing {
+			c.stats.Blocks++
+			c.stats.Tags += len(reTagToken.FindAllStringIndex(child.String, -1))
+		}
+
+		s := c.sourceText(&child)
+		query := isQuery(s)
+		if query {
+			var keep bool
+			if s, keep = c.queryBlock(s, child.UID); !keep {
+				continue
+			}
+		}
+
+		// a task is a checkbox list item, which a heading can't be
+		task := false
+		if !query && child.Heading == 0 {
+			s, task = c.taskText(s)
+		}
+
+		quoted := false
+		if !query && !task && !hasFence(s) {
+			s, quoted = quoteText(s)
+		}
+		callout := quoted && c.opts.Quotes == QuotesCallout
+
+		heading := ""
+		if child.Heading > 0 {
+			heading = strings.Repeat("#", child.Heading) + " "
+		}
+
+		// headings can't be nested in Markdown, below the top level they
+		// are bold text unless asked to stay headings
+		nested := (layout == layoutIndent && level > 0) || (layout == layoutOutline && depth > 0)
+		bold, kept := false, false
+		if heading != "" && nested && !c.logseq() {
+			switch c.opts.NestedHeadings {
+			case HeadingKeep:
+				if layout == layoutIndent {
+					// a heading must start the line, so it loses its
+					// place in the outline
+					prefix, indent = "", ""
+					kept = true
+				}
+			default:
+				heading = ""
+				bold = true
+			}
+		}
+
+		marker := c.opts.BulletMarker + " "
+		if numbered {
+			marker = fmt.Sprintf("%d. ", i+1)
+		}
+
+		switch layout {
+		case layoutOutline:
+			unit := c.indentUnit("\t")
+			switch c.opts.Target {
+			case TargetLogseq, TargetHTML:
+				// list styles are properties in Logseq and HTML keeps
+				// to plain lists
+				marker = "- "
+				if c.opts.Target == TargetHTML {
+					unit = "  "
+				}
+			}
+			prefix = strings.Repeat(unit, depth) + marker + heading
+			indent = strings.Repeat(unit, depth) + strings.Repeat(" ", len(marker))
+		case layoutParagraph:
+			prefix = heading
+			if task {
+				prefix = marker
+				indent = strings.Repeat(" ", len(marker))
+			}
+		default:
+			prefix = heading + prefix
+
+			if heading == "" && (task || (len(child.Children()) > 0 && level > 0)) {
+				prefix += marker
+				indent += strings.Repeat(" ", len(marker))
+			}
+
+			// indented fences and callouts outside a list are read as
+			// indented code, so they start the line
+			if _, wrapped := wrapperFor(s); level > 0 && (wrapped || (query && c.opts.Queries == QueryCallout) || callout || hasFence(s)) {
+				prefix, indent = heading, ""
+			}
+		}
+
+		// Logseq keeps block ids and list styles in properties below the
+		// block's text.
+		postfix := ""
+		var properties []string
+		// copies of embedded children don't repeat the anchors of the
+		// blocks they copy
+		if _, ok := c.referencedUID[child.UID]; ok && len(c.embedding) == 0 {
+			switch c.opts.Target {
+			case TargetLogseq:
+				properties = append(properties, indent+"id:: "+logseqBlockID(child.UID))
+			case TargetHTML:
+				postfix = fmt.Sprintf(` <a id="%s"></a>`, child.UID)
+			default:
+				postfix = fmt.Sprintf(" ^%s", child.UID)
+			}
+		}
+		if c.logseq() && numbered {
+			properties = append(properties, indent+"logseq.order-list-type:: number")
+		}
+
+		if w, ok := wrapperFor(s); ok {
+			lines = append(lines, c.expandWrapper(w, &child, prefix, indent)...)
+			if postfix != "" {
+				lines = append(lines, indent+strings.TrimSpace(postfix))
+			}
+			lines = append(lines, properties...)
+			if layout == layoutParagraph {
+				lines = append(lines, "")
+			}
+			continue
+		}
+
+		// code is copied as written, so each step only sees the text
+		// around it
+		updated, err := outsideCodeErr(s, func(text string) (string, error) {
+			return c.replaceBlockRefs(text, child.UID)
+		})
+		if err != nil {
+			return nil, err
+		}
+		updated = outsideCode(updated, replaceMath)
+
+		if c.opts.BareDates != "" {
+			updated = outsideCode(updated, c.replaceBareDates)
+		}
+
+		if c.opts.LinkEmails {
+			updated = outsideCode(updated, c.linkEmailAddresses)
+		}
+
+		if c.opts.TagsToEnd {
+			updated = moveTagsToEnd(updated)
+		}
+		updated = outsideCode(updated, c.slashTags)
+
+		if c.opts.UnlinkExcluded {
+			updated = outsideCode(updated, c.unlinkExcluded)
+		}
+		updated = outsideCode(updated, c.rewriteLinks)
+		updated = outsideCode(updated, func(text string) string {
+			return c.sizeImages(text, &child)
+		})
+
+		if len(c.opts.Replacements) > 0 {
+			updated = c.replaceMarkdown(child.UID, updated)
+		}
+
+		if c.opts.AnnotationStyle == AnnotationFootnote {
+			updated += c.annotationRefs(child.UID)
+		}
+
+		if bold {
+			updated = boldText(updated)
+		}
+		updated = c.align(updated, child.TextAlign)
+		updated += c.authorSuffix(&child, updated)
+		updated += c.reactions(&child)
+
+		if _, ok := c.ankiUIDs[child.UID]; ok && c.writing && len(c.embedding) == 0 {
+			if err := c.addAnkiCard(&child, updated); err != nil {
+				return nil, err
+			}
+		}
+
+		if updated != child.String {
+			c.log.Trace("convert block", "page", c.page.Title, "uid", child.UID, "from", child.String, "to", updated)
+		}
+
+		if postfix != "" && endsWithFence(updated) {
+			// an anchor after the closing fence would break it, it goes on
+			// the next line
+			postfix = "\n" + strings.TrimSpace(postfix)
+		}
+
+		switch {
+		case callout:
+			// the children are the body of the callout, and its anchor
+			// follows it after a blank line
+			body, err := c.expandChildren(&child, 0)
+			if err != nil {
+				return nil, err
+			}
+			updated = quoteCallout(updated, body)
+			if postfix != "" {
+				postfix = "\n\n" + strings.TrimSpace(postfix)
+			}
+		case quoted:
+			updated = quoteLines(updated)
+		}
+
+		s = prefix + updated + postfix
+		switch layout {
+		case layoutOutline:
+			s = strings.ReplaceAll(s, "\n", "\n"+indent)
+		case layoutIndent:
+			if strings.ContainsRune(s, '\n') {
+				s = strings.ReplaceAll(s, "\n", "\n"+indent) + "\n"
+			}
+		}
+
+		lines = append(lines, s)
+		lines = append(lines, properties...)
+
+		if c.opts.AnnotationStyle == AnnotationCallout {
+			lines = append(lines, c.annotationCallouts(child.UID, indent)...)
+		}
+
+		childLevel := level + 1
+		if kept {
+			// children continue below the heading as they would below
+			// a top-level one
+			childLevel = 1
+		}
+		if layout == layoutParagraph {
+			lines = append(lines, "")
+
+			// the children of a block whose children have none are
+			// paragraphs too
+			if shallow(&child) {
+				childLevel = 0
+			}
+		}
+
+		if callout {
+			continue
+		}
+
+		expanded, err := c.expandChildren(&child, childLevel)
+		if err != nil {
+			return nil, err
+		}
+
+		lines = append(lines, expanded...)
+		if layout == layoutParagraph && childLevel > 0 && len(expanded) > 0 {
+			lines = append(lines, "")
+		}
+	}
+
+	return lines, nil
+}
+
+// sourceText returns the text of block with its hiccup and components
+// converted, ready for its block refs to be expanded.
+func (c *Converter) sourceText(block *roam.Child) string {
+	s := block.String
+	if isHiccup(s) {
+		s = replaceHiccup(s, c.opts.HiccupFallback, !c.opts.MobileSafe)
+	}
+	s = c.smartBlocks(s, block)
+
+	return outsideCode(s, func(text string) string {
+		return c.replaceComponents(text, block.UID)
+	})
+}
+
+// boldText makes each line of s bold.
+func boldText(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			lines[i] = "**" + trimmed + "**"
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// shallow reports whether none of the children of block have children.
+func shallow(block *roam.Child) bool {
+	for _, child := range block.Children() {
+		if len(child.Children()) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *Converter) replaceBlockRefs(s, blockUID string) (string, error) {
+	// need to replay block embeds, block mentions, block refs with some text
+	update, _ := c.expandRefs(s, []string{blockUID})
+
+	return c.replaceDayLinks(update)
+}
+
+// expandRefs replaces the block refs in s, the text of the last block in path,
+// with the text of the blocks they reference. Referenced text is expanded in
+// turn, so path holds the chain of blocks being expanded. A reference back
+// into the chain, or one deeper than MaxRefDepth, is written as a link only
+// and reported. Referenced blocks get their hiccup and components converted
+// first, and refs in code are left alone. It also reports whether the
+// expansion was complete, with no chain cut short.
+//
+// s is scanned once from left to right for embeds, mentions and refs, and the
+// expansion of a referenced block is remembered when it was complete, so
+// blocks that are referenced often are only expanded once.
+func (c *Converter) expandRefs(s string, path []string) (string, bool) {
+	blockUID := path[len(path)-1]
+	top := len(path) == 1
+
+	// newer exports list a block's refs, so ((uid)) text naming a block
+	// that isn't a ref is left alone. Refs to deleted blocks aren't listed,
+	// so uids of unknown blocks are still written as missing refs.
+	refs, listed := c.blockRefs(blockUID)
+	if !strings.Contains(s, "((") {
+		return s, true
+	}
+
+	var sb strings.Builder
+	last := 0
+	complete := true
+	spans := reCode.FindAllStringIndex(s, -1)
+
+	for _, match := range reAnyBlockRef.FindAllStringSubmatchIndex(s, -1) {
+		if inCode(spans, match[0], match[1]) {
+			continue
+		}
+
+		embed := false
+		kind := ""
+		var uid string
+		switch {
+		case match[4] >= 0:
+			kind = s[match[2]:match[3]]
+			embed = kind != "mentions"
+			uid = s[match[4]:match[5]]
+		default:
+			uid = s[match[6]:match[7]]
+		}
+
+		child, ok := c.uidBlock[uid]
+		if _, ref := refs[uid]; listed && ok && !ref {
+			continue
+		}
+		if !ok {
+			// nested refs are reported on their own page
+			if top {
+				if c.writing {
+					c.stats.BlockRefsUnresolved++
+				}
+				c.addUnresolved(uid, blockUID)
+			}
+			if c.opts.MissingRefs == MissingRefsPlaceholder {
+				sb.WriteString(s[last:match[0]])
+				sb.WriteString(missingRef(uid))
+				last = match[1]
+			}
+			continue
+		}
+
+		if c.writing && top {
+			c.stats.BlockRefsResolved++
+		}
+
+		c.referencedUID[uid] = struct{}{}
+		sb.WriteString(s[last:match[0]])
+		last = match[1]
+
+		if c.logseq() {
+			sb.WriteString(logseqBlockRef(child.UID, embed))
+			continue
+		}
+
+		chain := append(append([]string{}, path...), uid)
+		switch {
+		case containsString(path, uid) || c.embedsItself(kind, child, path):
+			c.addRefChain(path[0], chain, RefChainCycle)
+			complete = false
+		case len(path) > c.opts.MaxRefDepth:
+			c.addRefChain(path[0], chain, RefChainDepth)
+			complete = false
+		case kind == embedChildren:
+			text, err := c.embeddedChildren(child)
+			if err != nil {
+				c.log.Warn("embed children", "page", c.page.Title, "block", blockUID, "error", err.Error())
+				break
+			}
+			fmt.Fprintf(&sb, "[[%s#^%s]]%s", child.Page.Title, child.UID, text)
+			continue
+		default:
+			if kind == embedPath {
+				sb.WriteString(c.embedPathContext(child))
+			}
+
+			text, ok := c.refText[uid]
+			if !ok {
+				var done bool
+				text, done = c.expandRefs(c.sourceText(child), chain)
+				if done {
+					c.refText[uid] = text
+				} else {
+					complete = false
+				}
+			}
+
+			// fenced code can't be copied into a line of text, so code
+			// blocks are embedded instead
+			if hasFence(text) {
+				if c.opts.Target == TargetObsidian {
+					sb.WriteString("!")
+				}
+				break
+			}
+
+			sb.WriteString(text)
+			if _, ok := c.excluded[child.Page.Title]; ok && c.opts.SkippedRefs == SkippedRefsText {
+				continue
+			}
+			sb.WriteString(" ")
+		}
+		fmt.Fprintf(&sb, "[[%s#^%s]]", child.Page.Title, child.UID)
+	}
+
+	if last == 0 {
+		return s, complete
+	}
+	sb.WriteString(s[last:])
+
+	return sb.String(), complete
+}
+
+// blockRefs returns the uids of the blocks referenced by the block with uid
+// blockUID, and whether the export lists its refs at all.
+func (c *Converter) blockRefs(blockUID string) (map[string]struct{}, bool) {
+	block, ok := c.uidBlock[blockUID]
+	if !ok || block.Refs == nil {
+		return nil, false
+	}
+
+	refs := map[string]struct{}{}
+	for _, ref := range block.Refs {
+		// page refs are listed too
+		if _, ok := c.uidBlock[ref.UID]; ok {
+			refs[ref.UID] = struct{}{}
+		}
+	}
+
+	return refs, true
+}
+
+// collectBlocks indexes children and the blocks below them by UID. The index
+// points into the page tree rather than holding copies, and every block
+// points back at page.
+func collectBlocks(uidList map[string]*roam.Child, page *roam.Page, children []roam.Child) {
+	for i := range children {
+		child := &children[i]
+		child.Page = page
+		uidList[child.UID] = child
+		collectBlocks(uidList, page, child.RawChildren)
+	}
+}
+
+var (
+	reBlockRef = regexp.MustCompile(`(\(\()(.{9})(\)\))`)
+	// reAnyBlockRef matches an embed or mention of a block, or a plain ref.
+	// Embeds may be of the block's children or path.
+	reAnyBlockRef = regexp.MustCompile(`{{(embed|embed-children|embed-path|mentions): \(\((.{9})\)\)}}|\(\((.{9})\)\)`)
+)
@@ -0,0 +1,86 @@
+package convert
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// stripTitleBrackets gives pages whose title contains [[links]] a filename
+// without the brackets, since a file named after the title can't be linked
+// to. Links to the page are rewritten by bracketLinks.
+func (c *Converter) stripTitleBrackets(pages []roam.Page) {
+	for i, page := range pages {
+		if _, ok := c.quarantined[i]; ok || page.IsDaily {
+			continue
+		}
+
+		stripped := stripBrackets(page.Title)
+		if stripped == page.Title || stripped == "" {
+			continue
+		}
+
+		c.log.Debug("strip title brackets", "page", page.Title, "title", stripped)
+
+		c.renamed[page.Title] = stripped
+		c.bracketed = append(c.bracketed, page.Title)
+	}
+
+	// a title can contain another, so the longest is matched first
+	sort.Slice(c.bracketed, func(i, j int) bool {
+		return len(c.bracketed[i]) > len(c.bracketed[j])
+	})
+}
+
+func stripBrackets(s string) string {
+	s = strings.NewReplacer("[[", "", "]]", "").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// bracketLinks rewrites links to pages with brackets in their title, which
+// the wikilink pattern can't match because they nest. The link text is the
+// title without brackets.
+func (c *Converter) bracketLinks(s string) string {
+	for _, title := range c.bracketed {
+		open := "[[" + title
+		if !strings.Contains(s, open) {
+			continue
+		}
+
+		var sb strings.Builder
+		for {
+			i := strings.Index(s, open)
+			if i < 0 {
+				break
+			}
+			rest := s[i+len(open):]
+			end := strings.Index(rest, "]]")
+			if end < 0 || !(end == 0 || rest[0] == '#' || rest[0] == '|') {
+				sb.WriteString(s[:i+len(open)])
+				s = rest
+				continue
+			}
+
+			anchor, text := rest[:end], stripBrackets(title)
+			if j := strings.Index(anchor, "|"); j >= 0 {
+				anchor, text = anchor[:j], anchor[j+1:]
+			}
+
+			sb.WriteString(s[:i])
+			switch {
+			case c.logseq():
+				sb.WriteString(logseqLink(c.fileTitle(title), text))
+			case anchor == "" && text == c.fileTitle(title):
+				sb.WriteString("[[" + text + "]]")
+			default:
+				sb.WriteString("[[" + c.fileTitle(title) + anchor + "|" + text + "]]")
+			}
+			s = rest[end+2:]
+		}
+		sb.WriteString(s)
+		s = sb.String()
+	}
+
+	return s
+}
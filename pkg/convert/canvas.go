@@ -0,0 +1,178 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// Canvas layout, in canvas pixels.
+const (
+	canvasBlockWidth  = 400
+	canvasPageWidth   = 300
+	canvasPageHeight  = 60
+	canvasLineHeight  = 24
+	canvasGap         = 40
+	canvasPageColumnX = canvasBlockWidth + 200
+)
+
+// canvas is an Obsidian .canvas file.
+type canvas struct {
+	Nodes []canvasNode `json:"nodes"`
+	Edges []canvasEdge `json:"edges"`
+}
+
+type canvasNode struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Text   string `json:"text,omitempty"`
+	File   string `json:"file,omitempty"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type canvasEdge struct {
+	ID       string `json:"id"`
+	FromNode string `json:"fromNode"`
+	FromSide string `json:"fromSide"`
+	ToNode   string `json:"toNode"`
+	ToSide   string `json:"toSide"`
+}
+
+// writeCanvases writes a canvas next to each page named by the CanvasFor
+// option. Every top-level block becomes a text card in a column, and the
+// pages it links to become file cards joined to it by an edge.
+func (c *Converter) writeCanvases(pages []roam.Page) error {
+	byTitle := map[string]*roam.Page{}
+	for i := range pages {
+		if _, ok := c.quarantined[i]; ok {
+			continue
+		}
+		if _, ok := c.skipped[i]; ok {
+			continue
+		}
+		byTitle[pages[i].Title] = &pages[i]
+	}
+
+	// the cards are rendered like the page, without counting the blocks
+	// again
+	c.writing = false
+
+	for _, title := range c.opts.CanvasFor {
+		if t, ok, err := c.parseDailyTitle(title); ok && err == nil {
+			title = c.formatDaily(t)
+		}
+
+		page, ok := byTitle[title]
+		if !ok {
+			c.log.Warn("canvas page not found", "page", title)
+			continue
+		}
+
+		cv, err := c.pageCanvas(page, byTitle)
+		if err != nil {
+			return fmt.Errorf("canvas for %q: %w", title, err)
+		}
+
+		data, err := json.MarshalIndent(cv, "", "\t")
+		if err != nil {
+			return err
+		}
+
+		dest := c.pagePath(page) + ".canvas"
+		c.log.Debug("write canvas", "page", page.Title, "path", dest)
+		if err := c.w.WriteFile(dest, append(data, '\n')); err != nil {
+			return err
+		}
+		c.stats.Assets++
+	}
+
+	return nil
+}
+
+func (c *Converter) pageCanvas(page *roam.Page, byTitle map[string]*roam.Page) (*canvas, error) {
+	cv := &canvas{Nodes: []canvasNode{}, Edges: []canvasEdge{}}
+	pageNodes := map[string]string{}
+	blockY, pageY := 0, 0
+
+	c.page = page
+	for _, block := range page.Children() {
+		lines, err := c.expandChildren(&roam.Child{RawChildren: []roam.Child{block}}, 0)
+		if err != nil {
+			return nil, err
+		}
+		text := strings.TrimRight(strings.Join(lines, "\n"), "\n")
+
+		height := canvasGap + canvasLineHeight*(strings.Count(text, "\n")+1)
+		blockID := "block-" + block.UID
+		cv.Nodes = append(cv.Nodes, canvasNode{
+			ID: blockID, Type: "text", Text: text,
+			X: 0, Y: blockY, Width: canvasBlockWidth, Height: height,
+		})
+		blockY += height + canvasGap
+
+		for _, target := range c.blockLinks(&block) {
+			linked, ok := byTitle[target]
+			if !ok || linked == page {
+				continue
+			}
+
+			pageID, ok := pageNodes[target]
+			if !ok {
+				pageID = fmt.Sprintf("page-%d", len(pageNodes)+1)
+				pageNodes[target] = pageID
+				cv.Nodes = append(cv.Nodes, canvasNode{
+					ID: pageID, Type: "file", File: c.pagePath(linked) + ".md",
+					X: canvasPageColumnX, Y: pageY, Width: canvasPageWidth, Height: canvasPageHeight,
+				})
+				pageY += canvasPageHeight + canvasGap
+			}
+
+			cv.Edges = append(cv.Edges, canvasEdge{
+				ID:       fmt.Sprintf("edge-%d", len(cv.Edges)+1),
+				FromNode: blockID, FromSide: "right",
+				ToNode: pageID, ToSide: "left",
+			})
+		}
+	}
+
+	return cv, nil
+}
+
+// blockLinks returns the titles of the pages a block and its children link
+// to, in order of first use.
+func (c *Converter) blockLinks(block *roam.Child) []string {
+	var titles []string
+	seen := map[string]struct{}{}
+
+	var walk func(child *roam.Child)
+	walk = func(child *roam.Child) {
+		targets := linkTargets(child.String)
+		for _, m := range reBlockRef.FindAllStringSubmatch(child.String, -1) {
+			if ref, ok := c.uidBlock[m[2]]; ok {
+				targets = append(targets, ref.Page.Title)
+			}
+		}
+
+		for _, target := range targets {
+			if t, ok, err := c.parseDailyTitle(target); ok && err == nil {
+				target = c.formatDaily(t)
+			}
+			if _, ok := seen[target]; !ok {
+				seen[target] = struct{}{}
+				titles = append(titles, target)
+			}
+		}
+
+		for i := range child.RawChildren {
+			walk(&child.RawChildren[i])
+		}
+	}
+	walk(block)
+
+	return titles
+}
@@ -0,0 +1,148 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+	"github.com/bryanl/goram2obs/pkg/vault"
+)
+
+const (
+	// checkpointFile records the pages written so far while a conversion
+	// runs, but not the assets written at its end. It is removed once the
+	// conversion finishes.
+	checkpointFile = ".goroam2obs-checkpoint.json"
+	// checkpointEvery is how many written pages go between saves.
+	checkpointEvery = 200
+)
+
+// checkpoint is the progress of an unfinished conversion.
+type checkpoint struct {
+	Pages map[string]checkpointPage `json:"pages"`
+}
+
+// checkpointPage is a page that was written, with the edit time it had in
+// the export and the statistics counted while writing it.
+type checkpointPage struct {
+	Edit                int    `json:"edit"`
+	Path                string `json:"path"`
+	Blocks              int    `json:"blocks"`
+	Tags                int    `json:"tags"`
+	BlockRefsResolved   int    `json:"block_refs_resolved"`
+	BlockRefsUnresolved int    `json:"block_refs_unresolved"`
+}
+
+// loadCheckpoint reads the checkpoint left in the vault by an interrupted
+// run, so its pages aren't written again.
+func (c *Converter) loadCheckpoint() error {
+	r, ok := c.w.(vault.Reader)
+	if !ok {
+		return errors.New("resume needs an output that can be read back")
+	}
+
+	data, err := r.ReadFile(checkpointFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.log.Info("no checkpoint to resume from, converting every page")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read checkpoint: %w", err)
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return fmt.Errorf("read checkpoint: %w", err)
+	}
+
+	c.log.Info("resuming from checkpoint", "pages", len(cp.Pages))
+	c.resumed = cp.Pages
+
+	return nil
+}
+
+// resumePage skips writing a page that an earlier run wrote to dest, unless
+// the page was edited since. The note and its statistics are counted as if
+// it had been written.
+func (c *Converter) resumePage(page *roam.Page, dest string) (bool, error) {
+	cp, ok := c.resumed[page.Title]
+	if !ok || cp.Edit != page.RawEditTime || cp.Path != dest {
+		return false, nil
+	}
+
+	c.stats.Pages++
+	if page.IsDaily {
+		c.stats.DailyNotes++
+	}
+	c.stats.Blocks += cp.Blocks
+	c.stats.Tags += cp.Tags
+	c.stats.BlockRefsResolved += cp.BlockRefsResolved
+	c.stats.BlockRefsUnresolved += cp.BlockRefsUnresolved
+	c.addNote(dest, c.noteDate(page))
+
+	return true, c.checkpointPage(page.Title, cp)
+}
+
+// recordPage adds a page written to dest to the checkpoint. before is the
+// statistics before it was expanded. Pages that left output for the end of
+// the run, such as drawings or report entries, aren't recorded, so resuming
+// writes them again.
+func (c *Converter) recordPage(page *roam.Page, dest string, before Stats, deferred int) error {
+	if c.deferredOutputs() != deferred {
+		return nil
+	}
+
+	return c.checkpointPage(page.Title, checkpointPage{
+		Edit:                page.RawEditTime,
+		Path:                dest,
+		Blocks:              c.stats.Blocks - before.Blocks,
+		Tags:                c.stats.Tags - before.Tags,
+		BlockRefsResolved:   c.stats.BlockRefsResolved - before.BlockRefsResolved,
+		BlockRefsUnresolved: c.stats.BlockRefsUnresolved - before.BlockRefsUnresolved,
+	})
+}
+
+func (c *Converter) checkpointPage(title string, cp checkpointPage) error {
+	c.checkpoint.Pages[title] = cp
+	if len(c.checkpoint.Pages)%checkpointEvery != 0 {
+		return nil
+	}
+
+	return c.saveCheckpoint()
+}
+
+// deferredOutputs counts the output collected while writing pages and
+// written at the end of the run.
+func (c *Converter) deferredOutputs() int {
+	return len(c.drawings) + len(c.snippets) + len(c.contacts) + len(c.renderUses) + len(c.report.Queries) + len(c.ankiCards)
+}
+
+func (c *Converter) saveCheckpoint() error {
+	raw, err := json.Marshal(c.checkpoint)
+	if err != nil {
+		return err
+	}
+
+	if err := c.w.WriteFile(checkpointFile, raw); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// removeCheckpoint removes the checkpoint of a finished run, when the vault
+// supports removing files.
+func (c *Converter) removeCheckpoint() error {
+	rm, ok := c.w.(vault.Remover)
+	if !ok {
+		return nil
+	}
+
+	if err := rm.Remove(checkpointFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove checkpoint: %w", err)
+	}
+
+	return nil
+}
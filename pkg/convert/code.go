@@ -0,0 +1,76 @@
+package convert
+
+import (
+	"regexp"
+	"strings"
+)
+
+// outsideCode applies fn to the parts of s that aren't inline code or code
+// blocks, so Roam syntax in code is left as written.
+func outsideCode(s string, fn func(string) string) string {
+	out, _ := outsideCodeErr(s, func(text string) (string, error) {
+		return fn(text), nil
+	})
+
+	return out
+}
+
+// outsideCodeErr is outsideCode for transformations that can fail.
+func outsideCodeErr(s string, fn func(string) (string, error)) (string, error) {
+	if !strings.Contains(s, "`") {
+		return fn(s)
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range reCode.FindAllStringIndex(s, -1) {
+		text, err := fn(s[last:m[0]])
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(text)
+		sb.WriteString(s[m[0]:m[1]])
+		last = m[1]
+	}
+
+	text, err := fn(s[last:])
+	if err != nil {
+		return "", err
+	}
+	sb.WriteString(text)
+
+	return sb.String(), nil
+}
+
+// hasFence reports whether s has a line that opens or closes fenced code.
+func hasFence(s string) bool {
+	return reFenceLine.MatchString(s)
+}
+
+// endsWithFence reports whether the last line of s closes fenced code, which
+// may be quoted.
+func endsWithFence(s string) bool {
+	s = strings.TrimRight(s, " \t\n")
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		s = s[i+1:]
+	}
+	s = strings.TrimLeft(s, "> \t")
+
+	return strings.TrimSpace(s) == "```" && strings.Count(s, "`") == 3
+}
+
+// inCode reports whether s[start:end] is inside one of the code spans.
+func inCode(spans [][]int, start, end int) bool {
+	for _, span := range spans {
+		if start < span[1] && end > span[0] {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	reCode      = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+	reFenceLine = regexp.MustCompile("(?m)^[ \t]*```")
+)
@@ -0,0 +1,264 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// Collision policies.
+const (
+	CollisionWarn   = "warn"
+	CollisionError  = "error"
+	CollisionSuffix = "suffix"
+	CollisionMerge  = "merge"
+)
+
+// Collision is a set of page titles that map to the same file on a case
+// insensitive filesystem.
+type Collision struct {
+	Titles []string `json:"titles"`
+	// Renamed maps titles that were given a new filename to that filename.
+	Renamed map[string]string `json:"renamed,omitempty"`
+	// Merged is the title of the note the pages were merged into.
+	Merged string `json:"merged,omitempty"`
+}
+
+// detectCollisions finds pages whose destination paths only differ by case
+// and applies the collision policy to them. Under CollisionSuffix every page
+// after the first in a collision gets a numeric suffix, and under
+// CollisionMerge they are written into the note of the first. Either way
+// links to them are rewritten.
+func (c *Converter) detectCollisions(pages []roam.Page, policy string) error {
+	byKey := map[string][]int{}
+	var keys []string
+
+	for i, page := range pages {
+		if _, ok := c.quarantined[i]; ok || page.Title == "" {
+			continue
+		}
+
+		key := strings.ToLower(c.pagePath(&page))
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], i)
+	}
+
+	taken := map[string]struct{}{}
+	for _, key := range keys {
+		taken[key] = struct{}{}
+	}
+
+	for _, key := range keys {
+		indexes := byKey[key]
+		if len(indexes) < 2 {
+			continue
+		}
+
+		titles := make([]string, len(indexes))
+		for j, i := range indexes {
+			titles[j] = pages[i].Title
+		}
+
+		if policy == CollisionError {
+			return fmt.Errorf("filename collision: %s", strings.Join(titles, ", "))
+		}
+
+		c.log.Warn("filename collision", "titles", strings.Join(titles, ", "))
+
+		col := Collision{Titles: titles}
+		switch policy {
+		case CollisionSuffix:
+			col.Renamed = map[string]string{}
+			seen := map[string]struct{}{titles[0]: {}}
+			for _, i := range indexes[1:] {
+				page := &pages[i]
+				suffix := c.collisionSuffix(page, taken)
+
+				// links can only be pointed at a page whose title is its
+				// own, so a page with the title of another, such as a
+				// second daily page for a date, only has its file renamed
+				_, dup := seen[page.Title]
+				if dup || page.IsDaily {
+					page.FileSuffix = suffix
+				} else {
+					c.renamed[page.Title] = c.fileTitle(page.Title) + suffix
+					seen[page.Title] = struct{}{}
+				}
+
+				if _, ok := col.Renamed[page.Title]; !ok {
+					col.Renamed[page.Title] = c.fileTitle(page.Title) + page.FileSuffix
+				}
+			}
+		case CollisionMerge:
+			col.Merged = titles[0]
+			for _, title := range titles {
+				c.collided[title] = titles[0]
+			}
+		}
+
+		c.report.Collisions = append(c.report.Collisions, col)
+	}
+
+	return nil
+}
+
+// addCollidedSection adds the lines of a page merged after a collision to
+// the note it is written to. The frontmatter of the first page is kept.
+func (c *Converter) addCollidedSection(page *roam.Page, dest string, lines []string) {
+	first := c.collided[page.Title]
+	if first != page.Title {
+		dest = c.pagePath(&roam.Page{Title: first, IsDaily: page.IsDaily}) + ".md"
+	}
+
+	section := mergedSection{date: c.localTime(page.CreateTime), title: page.Title, lines: lines}
+	if first == page.Title {
+		section.front = c.frontmatter(page)
+	}
+
+	if _, ok := c.collidedSections[dest]; !ok {
+		c.collidedOrder = append(c.collidedOrder, dest)
+	}
+	c.collidedSections[dest] = append(c.collidedSections[dest], section)
+}
+
+// writeCollided writes the notes of merged collisions with one section per
+// page, in the order the pages were exported.
+func (c *Converter) writeCollided() error {
+	for _, dest := range c.collidedOrder {
+		sections := c.collidedSections[dest]
+
+		var lines []string
+		for _, section := range sections {
+			lines = append(lines, section.front...)
+		}
+		for _, section := range sections {
+			lines = append(lines, "## "+section.title)
+			lines = append(lines, section.lines...)
+			lines = append(lines, "")
+		}
+
+		if err := c.w.WriteFile(dest, []byte(strings.Join(lines, "\n"))); err != nil {
+			return err
+		}
+		c.addNote(dest, sections[0].date)
+	}
+
+	return nil
+}
+
+// collisionSuffix returns the lowest numeric suffix that gives page a path
+// that doesn't clash with a lowercased path in taken, and marks the path as
+// taken.
+func (c *Converter) collisionSuffix(page *roam.Page, taken map[string]struct{}) string {
+	candidate := *page
+	for n := 2; ; n++ {
+		candidate.FileSuffix = fmt.Sprintf(" (%d)", n)
+		key := strings.ToLower(c.pagePath(&candidate))
+		if _, ok := taken[key]; !ok {
+			taken[key] = struct{}{}
+			return candidate.FileSuffix
+		}
+	}
+}
+
+// fileTitle returns the name used for a page's file and for links to it.
+func (c *Converter) fileTitle(title string) string {
+	if renamed, ok := c.renamed[title]; ok {
+		return renamed
+	}
+
+	return title
+}
+
+// rewriteLinks points wikilinks at renamed pages, merged daily notes and
+// merged collisions. The original title is kept as the link text so the note
+// reads the same.
+func (c *Converter) rewriteLinks(s string) string {
+	if (len(c.renamed) == 0 && len(c.merged) == 0 && len(c.collided) == 0) || !strings.Contains(s, "[[") {
+		return s
+	}
+
+	if len(c.bracketed) > 0 {
+		s = c.bracketLinks(s)
+	}
+
+	return reWikiLink.ReplaceAllStringFunc(s, func(link string) string {
+		inner := link[2 : len(link)-2]
+
+		target, alias := inner, ""
+		if i := strings.Index(inner, "|"); i >= 0 {
+			target, alias = inner[:i], inner[i:]
+		}
+
+		title, anchor := target, ""
+		if i := strings.Index(target, "#"); i >= 0 {
+			title, anchor = target[:i], target[i:]
+		}
+
+		if c.logseq() {
+			if renamed, ok := c.renamed[title]; ok {
+				text := title
+				if alias != "" {
+					text = alias[1:]
+				}
+				return logseqLink(renamed, text)
+			}
+			return link
+		}
+
+		merged, ok := c.merged[title]
+		if !ok {
+			merged, ok = c.collided[title]
+			ok = ok && merged != title
+		}
+		if ok {
+			if anchor == "" {
+				anchor = "#" + title
+			}
+			if alias == "" {
+				alias = "|" + title
+			}
+			return "[[" + merged + anchor + alias + "]]"
+		}
+
+		renamed, ok := c.renamed[title]
+		if !ok {
+			return link
+		}
+
+		if alias == "" {
+			alias = "|" + title
+		}
+
+		return "[[" + renamed + anchor + alias + "]]"
+	})
+}
+
+// pagePath returns the vault path of a page without the file extension.
+func (c *Converter) pagePath(page *roam.Page) string {
+	if c.logseq() {
+		return c.logseqPagePath(page) + page.FileSuffix
+	}
+
+	if page.IsDaily {
+		return c.dailyFolder() + c.fileTitle(page.Title) + page.FileSuffix
+	}
+
+	return c.fileTitle(page.Title) + page.FileSuffix
+}
+
+// dailyFolder returns the folder daily notes are written to, with a
+// trailing slash.
+func (c *Converter) dailyFolder() string {
+	if c.logseq() {
+		return "journals/"
+	}
+
+	return "daily/"
+}
+
+var reWikiLink = regexp.MustCompile(`\[\[([^\[\]]+)\]\]`)
@@ -0,0 +1,182 @@
+package convert
+
+import (
+	"strings"
+)
+
+// componentCall is a single {{name: args}} occurrence in a block.
+type componentCall struct {
+	// Name is the component name without page reference brackets, so
+	// {{embed}} and {{[[embed]]}} name the same component.
+	Name string
+	// Args is the text after the colon, trimmed.
+	Args string
+	// Raw is the full original text, braces included.
+	Raw string
+	// BlockUID is the uid of the block containing the call.
+	BlockUID string
+}
+
+// componentHandler converts a component call. It returns false to leave the
+// call untouched.
+type componentHandler func(c *Converter, call componentCall) (string, bool)
+
+// componentSpec describes a Roam component.
+type componentSpec struct {
+	Name    string
+	Aliases []string
+	Handle  componentHandler
+	// Wrapper is set for components whose children hold code. The
+	// component then replaces the whole block.
+	Wrapper *codeWrapper
+}
+
+type componentRegistry map[string]componentSpec
+
+func newComponentRegistry(specs ...componentSpec) componentRegistry {
+	r := componentRegistry{}
+	for _, spec := range specs {
+		r.register(spec)
+	}
+
+	return r
+}
+
+func (r componentRegistry) register(spec componentSpec) {
+	r[strings.ToLower(spec.Name)] = spec
+	for _, alias := range spec.Aliases {
+		r[strings.ToLower(alias)] = spec
+	}
+}
+
+func (r componentRegistry) handler(call componentCall) componentHandler {
+	return r[strings.ToLower(call.Name)].Handle
+}
+
+func (r componentRegistry) wrapper(call componentCall) *codeWrapper {
+	return r[strings.ToLower(call.Name)].Wrapper
+}
+
+// components are the components the Converter knows about.
+var components = newComponentRegistry(
+	componentSpec{
+		Name:   "roam/render",
+		Handle: (*Converter).renderComponent,
+	},
+	componentSpec{
+		Name:   "iframe",
+		Handle: (*Converter).iframe,
+	},
+	componentSpec{
+		Name:    "youtube",
+		Aliases: []string{"video", "pdf"},
+		Handle:  (*Converter).embedLink,
+	},
+	componentSpec{
+		Name:   "attr-table",
+		Handle: (*Converter).attrTable,
+	},
+	componentSpec{
+		Name:    "drawing",
+		Aliases: []string{"diagram"},
+		Handle:  (*Converter).drawing,
+	},
+	componentSpec{
+		Name:    "link-preview",
+		Aliases: []string{"preview"},
+		Handle:  (*Converter).linkPreview,
+	},
+	componentSpec{
+		Name:   "encrypt",
+		Handle: (*Converter).encryptedBlock,
+	},
+	componentSpec{
+		Name:    "∆",
+		Aliases: []string{"Δ"},
+		Handle:  (*Converter).srsItem,
+	},
+	componentSpec{Name: "mermaid", Wrapper: &codeWrapper{Lang: "mermaid"}},
+	componentSpec{Name: "htmlview", Wrapper: &codeWrapper{Lang: "html", Ext: "html"}},
+	componentSpec{Name: "roam/css", Wrapper: &codeWrapper{Lang: "css", Ext: "css"}},
+	componentSpec{Name: "roam/js", Wrapper: &codeWrapper{Lang: "javascript", Ext: "js"}},
+)
+
+// replaceComponents converts every registered component in s, the text of the
+// block with uid blockUID.
+func (c *Converter) replaceComponents(s, blockUID string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+
+	var sb strings.Builder
+	rest := s
+
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			break
+		}
+
+		end := componentEnd(rest, start)
+		if end < 0 {
+			break
+		}
+
+		sb.WriteString(rest[:start])
+
+		call := parseComponentCall(rest[start:end])
+		call.BlockUID = blockUID
+
+		replacement := call.Raw
+		if handle := components.handler(call); handle != nil {
+			if out, ok := handle(c, call); ok {
+				replacement = out
+			}
+		}
+		sb.WriteString(replacement)
+
+		rest = rest[end:]
+	}
+	sb.WriteString(rest)
+
+	return sb.String()
+}
+
+// componentEnd returns the offset just past the }} that closes the component
+// starting at s[start:], or -1 if it is not closed.
+func componentEnd(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s)-1; i++ {
+		switch s[i : i+2] {
+		case "{{":
+			depth++
+			i++
+		case "}}":
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return -1
+}
+
+func parseComponentCall(raw string) componentCall {
+	call := componentCall{Raw: raw}
+
+	inner := strings.TrimSpace(raw[2 : len(raw)-2])
+	name := inner
+	if i := strings.Index(inner, ":"); i >= 0 {
+		name, call.Args = inner[:i], strings.TrimSpace(inner[i+1:])
+	}
+
+	name = strings.TrimSpace(name)
+	if strings.HasPrefix(name, "[[") && strings.HasSuffix(name, "]]") {
+		name = name[2 : len(name)-2]
+	}
+	call.Name = name
+
+	return call
+}
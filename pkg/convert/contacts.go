@@ -0,0 +1,120 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// LoadUserMap reads a JSON object mapping Roam user emails, or the user uids
+// of exports that don't include emails, to display names.
+func LoadUserMap(userMapPath string) (map[string]string, error) {
+	data, err := os.ReadFile(userMapPath)
+	if err != nil {
+		return nil, err
+	}
+
+	m := map[string]string{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	normalized := make(map[string]string, len(m))
+	for email, name := range m {
+		normalized[strings.ToLower(email)] = name
+	}
+
+	return normalized, nil
+}
+
+// linkEmailAddresses replaces bare email addresses with links to a person
+// page. The page is named after the user map entry for the address, or the
+// address itself when it isn't mapped.
+func (c *Converter) linkEmailAddresses(s string) string {
+	var sb strings.Builder
+	last := 0
+
+	for _, match := range reEmail.FindAllStringIndex(s, -1) {
+		if !isBareEmail(s, match[0], match[1]) {
+			continue
+		}
+
+		email := s[match[0]:match[1]]
+		// slashes in a mapped name would nest the person page in
+		// folders
+		name := strings.NewReplacer("/", "-", "\\", "-").Replace(c.contactName(email))
+
+		sb.WriteString(s[last:match[0]])
+		sb.WriteString("[[" + name + "]]")
+		last = match[1]
+
+		c.contacts[name] = strings.ToLower(email)
+		if _, ok := c.renamed[name]; !ok && c.opts.Slug {
+			// contact pages are slugged like the pages of the export
+			c.renamed[name] = slugTitle(name)
+		}
+	}
+	sb.WriteString(s[last:])
+
+	return sb.String()
+}
+
+func (c *Converter) contactName(email string) string {
+	if name, ok := c.opts.UserMap[strings.ToLower(email)]; ok && name != "" {
+		return name
+	}
+
+	return email
+}
+
+// isBareEmail reports whether the address at s[start:end] is plain text rather
+// than part of a link, mailto URL or wikilink.
+func isBareEmail(s string, start, end int) bool {
+	head := s[:start]
+	if strings.HasSuffix(head, "mailto:") || strings.HasSuffix(head, "[[") || strings.HasSuffix(head, "(") {
+		return false
+	}
+
+	if open := strings.LastIndex(head, "[["); open >= 0 && !strings.Contains(head[open:], "]]") {
+		return false
+	}
+
+	return end == len(s) || s[end] != ']'
+}
+
+// writeContactPages creates a page for every linked contact that doesn't
+// already have one. Pages are compared by the paths they are written to.
+func (c *Converter) writeContactPages(pages []roam.Page) error {
+	existing := map[string]struct{}{}
+	for i := range pages {
+		existing[strings.ToLower(c.pagePath(&pages[i]))] = struct{}{}
+	}
+
+	names := make([]string, 0, len(c.contacts))
+	for name := range c.contacts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		dest := c.pagePath(&roam.Page{Title: name})
+		if _, ok := existing[strings.ToLower(dest)]; ok {
+			continue
+		}
+
+		email := c.contacts[name]
+		data := fmt.Sprintf("Email: [%s](mailto:%s)\n", email, email)
+		if err := c.w.WriteFile(dest+".md", []byte(data)); err != nil {
+			return fmt.Errorf("write contact page %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+var reEmail = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
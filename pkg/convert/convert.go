@@ -0,0 +1,1224 @@
+// Package convert turns Roam pages into Obsidian notes.
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+	"github.com/bryanl/goram2obs/pkg/vault"
+)
+
+// BulletNumbered numbers list items instead of using a bullet marker.
+const BulletNumbered = "1."
+
+// IndentTab indents nested blocks with a tab. See Options.Indent.
+const IndentTab = "tab"
+
+const (
+	// StyleIndent writes top-level blocks as lines and nested blocks as
+	// indented text, with a marker on blocks that have children.
+	StyleIndent = "indent"
+	// StyleOutline writes every block as a list item.
+	StyleOutline = "outline"
+	// StyleProse writes top-level blocks as paragraphs. Children are
+	// paragraphs too when they have no children of their own, and a list
+	// otherwise.
+	StyleProse = "prose"
+)
+
+const (
+	// HeadingBold writes nested heading blocks as bold text so they keep
+	// their place in the outline.
+	HeadingBold = "bold"
+	// HeadingKeep writes nested heading blocks as headings, which moves
+	// them out of their list.
+	HeadingKeep = "keep"
+)
+
+// Options configures a conversion. The zero value selects the defaults.
+type Options struct {
+	// Logger receives log messages. Defaults to discarding them.
+	Logger Logger
+	// Progress receives progress updates for each pass.
+	Progress Progress
+
+	// Target is TargetObsidian (default), TargetLogseq or TargetHTML.
+	Target string
+
+	// Annotations are merged into the blocks they are keyed by.
+	Annotations map[string][]Annotation
+	// AnnotationStyle is AnnotationFootnote (default) or AnnotationCallout.
+	AnnotationStyle string
+
+	// HiccupFallback is HiccupPassthrough (default) or HiccupStrip.
+	HiccupFallback string
+
+	// UserMap maps Roam user emails and uids to display names.
+	UserMap map[string]string
+	// LinkEmails links bare email addresses to person pages.
+	LinkEmails bool
+
+	// RenderPolicy is RenderCallout (default), RenderStrip or RenderReport.
+	RenderPolicy string
+
+	// DayStyle is DayStyleObsidian (default) or DayStyleRoam.
+	DayStyle string
+	// DailyPatterns detect daily notes with non-Roam titles.
+	DailyPatterns []*roam.DailyPattern
+
+	// Disambiguate adds a numeric suffix to pages whose filenames only
+	// differ by case. It is the same as CollisionSuffix.
+	Disambiguate bool
+	// OnCollision is what happens to pages that map to the same file:
+	// CollisionWarn (default) reports them, CollisionError fails the run,
+	// CollisionSuffix renames all but the first and CollisionMerge writes
+	// them as sections of one note.
+	OnCollision string
+
+	// SkipEmpty skips pages without content.
+	SkipEmpty bool
+	// SkipOrphans skips non-daily pages no other page links to.
+	SkipOrphans bool
+
+	// MaxFiles is the number of notes above which Shard strategies are
+	// applied. Zero disables the check.
+	MaxFiles int
+	// Shard lists strategies from ShardStrategies.
+	Shard []string
+
+	// CodeWrappers is WrapperFence (default) or WrapperSnippet.
+	CodeWrappers string
+
+	// Style is StyleIndent (default), StyleOutline or StyleProse.
+	Style string
+
+	// BulletMarker is the list marker: "-", "*", "+" or BulletNumbered.
+	// It defaults to "-" for outlines and "*" otherwise. Children of Roam
+	// numbered blocks are always numbered.
+	BulletMarker string
+
+	// Indent is what each level of nesting is indented by: IndentTab or a
+	// number of spaces, such as "2". It defaults to four spaces for
+	// StyleIndent and a tab for outlines.
+	Indent string
+
+	// NestedHeadings is HeadingBold (default) or HeadingKeep. Headings at
+	// the top level are always written as headings.
+	NestedHeadings string
+
+	// TagsToEnd moves tags to the end of their block.
+	TagsToEnd bool
+
+	// SlashTags is how tags with a slash, such as #projects/alpha, are
+	// written: SlashTagsTag (default, a nested tag), SlashTagsLink (a link
+	// to the namespaced page) or SlashTagsBoth.
+	SlashTags string
+
+	// Quotes is how quote blocks, starting with "> " or [[>]], are written:
+	// QuotesPlain (default, a blockquote) or QuotesCallout, a quote callout
+	// that holds the block's children too.
+	Quotes string
+
+	// Replacements are applied, in order, to the text of every block at
+	// their stage.
+	Replacements []*Replacement
+	// DryRun logs every change made by Replacements and writes nothing.
+	DryRun bool
+
+	// StripTitleEmoji removes leading emoji from filenames. The original
+	// title is kept as an alias.
+	StripTitleEmoji bool
+	// Slug writes pages, other than daily notes, to kebab-case ASCII
+	// filenames. The original title is kept in the frontmatter.
+	Slug bool
+
+	// FolderIndex writes an index of the notes in every folder:
+	// FolderIndexReadme, FolderIndexAbout, or nothing when empty.
+	FolderIndex string
+
+	// CanvasFor lists pages to also lay out as an Obsidian canvas.
+	CanvasFor []string
+
+	// AuthorCallouts wraps runs of top-level blocks created by someone
+	// other than the page's creator in a callout naming them. Names come
+	// from UserMap.
+	AuthorCallouts bool
+	// AuthorFrontmatter adds the display name of the page's creator to the
+	// frontmatter as author.
+	AuthorFrontmatter bool
+	// AuthorSuffix ends blocks created by someone other than the page's
+	// creator with " — name".
+	AuthorSuffix bool
+
+	// Exclude skips the pages whose title matches one of these patterns,
+	// compiled with CompileExclude.
+	Exclude []*regexp.Regexp
+
+	// Include skips the pages whose title matches none of these patterns,
+	// compiled with CompileExclude. IncludeLinked keeps the pages that
+	// included pages link to as well.
+	Include       []*regexp.Regexp
+	IncludeLinked bool
+	// Pages converts only the pages with these exact titles. Block refs
+	// still resolve against the whole export.
+	Pages []string
+
+	// DailyNotesConfig writes the Daily Notes core plugin settings, merged
+	// with any already in the vault, so new daily notes land next to the
+	// converted ones.
+	DailyNotesConfig bool
+
+	// UIDMap writes uid-map.json mapping page titles to note paths and
+	// block UIDs to their anchors. Every block gets an anchor.
+	UIDMap bool
+
+	// BacklinksSection appends a Backlinks section to every note listing
+	// the blocks that link to it.
+	BacklinksSection bool
+
+	// MOC writes an Index note grouping all pages, MOCLetter or
+	// MOCNamespace, and hub notes for namespaces. Empty writes none.
+	MOC string
+
+	// InitVault writes the .obsidian settings of a new vault: attachment
+	// and template folders, daily notes and core plugins. Settings already
+	// in the vault are kept.
+	InitVault bool
+
+	// RoamCodePages writes the code of roam/css pages to CSS snippets and
+	// that of roam/js pages to the roam-js folder, instead of writing the
+	// pages as notes.
+	RoamCodePages bool
+
+	// Templates writes the templates on the roam/templates page to the
+	// templates folder, set as the Templates core plugin's folder, instead
+	// of writing the page as a note.
+	Templates bool
+
+	// Anki writes the blocks tagged #anki or #flashcard, with their children
+	// as the answer, to a file Anki can import, linking back to the blocks.
+	Anki bool
+
+	// BareDates rewrites dates written as plain text: BareDatesText or
+	// BareDatesLink. Empty leaves them alone.
+	BareDates string
+
+	// DailyFrom and DailyTo skip the daily pages dated outside the range.
+	// A zero time leaves that end open.
+	DailyFrom time.Time
+	DailyTo   time.Time
+
+	// SkippedRefs is SkippedRefsLink (default) or SkippedRefsText. It
+	// applies to refs to blocks on pages left out by Exclude, Include or
+	// the daily range.
+	SkippedRefs string
+
+	// MissingRefs is how refs to blocks that aren't in the export are
+	// written: MissingRefsPlaceholder (default) or MissingRefsKeep. They
+	// are listed in the report either way.
+	MissingRefs string
+
+	// UnlinkExcluded turns links to excluded pages, and pages left out by
+	// Include, into plain text.
+	UnlinkExcluded bool
+
+	// Reactions is ReactionsDrop (default), ReactionsComment or
+	// ReactionsFrontmatter.
+	Reactions string
+
+	// TextAlign is AlignStrip (default) or AlignHTML.
+	TextAlign string
+
+	// MaxRefDepth is how deep block refs inside referenced blocks are
+	// expanded. Defaults to 10.
+	MaxRefDepth int
+
+	// KeepAliasBlocks keeps the alias:: blocks whose values were added to
+	// a page's aliases.
+	KeepAliasBlocks bool
+
+	// PageTags adds the tags of a page whose first block is only tags to
+	// its frontmatter: PageTagsCopy or PageTagsMove, which removes the
+	// block. Empty leaves the block alone.
+	PageTags string
+
+	// Location is the time zone Roam timestamps are shown in. The default
+	// is the local time zone.
+	Location *time.Location
+
+	// Queries is how :q datalog query blocks are written: QueryCallout
+	// (default, the query in a collapsed callout), QueryKeep or QueryStrip.
+	// Queries are listed in the report either way.
+	Queries string
+
+	// SmartBlocks is how SmartBlocks workflow syntax is written:
+	// SmartBlocksKeep (default), SmartBlocksStrip or SmartBlocksText. The
+	// blocks that held it are listed in the report unless it is kept.
+	SmartBlocks string
+
+	// SRS is how {{[[∆]]: n+m}} spaced repetition blocks are written:
+	// SRSKeep (default) or SRSFlashcards, a Spaced Repetition plugin card
+	// due when Roam would show the block again.
+	SRS string
+
+	// Tasks is how {{[[TODO]]}} and {{[[DONE]]}} blocks are written:
+	// TasksKeep (default) or TasksPlugin, Obsidian Tasks checkboxes with the
+	// block's daily note link as the due or done date.
+	Tasks string
+
+	// Widgets sets how widgets such as {{word-count}} and {{calc}} are
+	// written, by widget name: WidgetStrip, WidgetText, WidgetHTML or
+	// WidgetKeep.
+	// Widgets not listed get their default, see WidgetNames.
+	Widgets map[string]string
+
+	// Encrypted is how {{encrypt}} blocks are written: EncryptedStrip,
+	// EncryptedCallout (default, the ciphertext in a collapsed callout) or
+	// EncryptedDecrypt, which needs EncryptionPassword.
+	Encrypted          string
+	EncryptionPassword string
+
+	// Safe quarantines pages that fail to convert instead of aborting. It
+	// is the same as OnErrorWarn.
+	Safe bool
+
+	// OnError is what happens to a page that fails to convert: OnErrorFail
+	// (default) aborts the run, OnErrorWarn quarantines the page and
+	// OnErrorSkip leaves it out. Either way the run finishes and the page
+	// is listed in the report.
+	OnError string
+
+	// Resume skips the pages an interrupted run already wrote, as recorded
+	// in the checkpoint it left in the vault, unless they were edited since.
+	// Only pages are checkpointed: assets such as drawings, snippets and
+	// canvases are written again, along with the pages they come from.
+	Resume bool
+
+	// Strict fails the run on problems that are otherwise only reported,
+	// such as unresolved block references, before any note is written.
+	Strict bool
+
+	// MobileSafe writes a vault that behaves well in Obsidian Mobile: no
+	// HTML from hiccup, links instead of iframe embeds, and short, shallow
+	// filenames. Pages renamed this way are always disambiguated.
+	MobileSafe bool
+
+	// Iframes is how {{iframe}} components are written: IframeHTML
+	// (default), IframeSandbox or IframeLink, the default in mobile safe
+	// mode.
+	Iframes string
+}
+
+func (o *Options) setDefaults() {
+	if o.Logger == nil {
+		o.Logger = nopLogger{}
+	}
+	if o.Progress == nil {
+		o.Progress = nopProgress{}
+	}
+	if o.Target == "" {
+		o.Target = TargetObsidian
+	}
+	if o.Location == nil {
+		o.Location = time.Local
+	}
+	if o.Queries == "" {
+		o.Queries = QueryCallout
+	}
+	if o.MissingRefs == "" {
+		o.MissingRefs = MissingRefsPlaceholder
+	}
+	if o.Quotes == "" {
+		o.Quotes = QuotesPlain
+	}
+	if o.SlashTags == "" {
+		o.SlashTags = SlashTagsTag
+	}
+	if o.SmartBlocks == "" {
+		o.SmartBlocks = SmartBlocksKeep
+	}
+	if o.Iframes == "" {
+		o.Iframes = IframeHTML
+		if o.MobileSafe {
+			o.Iframes = IframeLink
+		}
+	}
+	if o.SRS == "" {
+		o.SRS = SRSKeep
+	}
+
+	if o.Tasks == "" {
+		o.Tasks = TasksKeep
+	}
+	if o.Encrypted == "" {
+		o.Encrypted = EncryptedCallout
+		if o.EncryptionPassword != "" {
+			o.Encrypted = EncryptedDecrypt
+		}
+	}
+	if o.OnCollision == "" {
+		o.OnCollision = CollisionWarn
+		if o.Disambiguate || o.MobileSafe {
+			o.OnCollision = CollisionSuffix
+		}
+	}
+	if o.OnError == "" {
+		o.OnError = OnErrorFail
+		if o.Safe {
+			o.OnError = OnErrorWarn
+		}
+	}
+	if o.AnnotationStyle == "" {
+		o.AnnotationStyle = AnnotationFootnote
+	}
+	if o.HiccupFallback == "" {
+		o.HiccupFallback = HiccupPassthrough
+	}
+	if o.RenderPolicy == "" {
+		o.RenderPolicy = RenderCallout
+	}
+	if o.DayStyle == "" {
+		o.DayStyle = DayStyleObsidian
+	}
+	if o.CodeWrappers == "" {
+		o.CodeWrappers = WrapperFence
+	}
+	if o.Style == "" {
+		o.Style = StyleIndent
+	}
+	if o.SkippedRefs == "" {
+		o.SkippedRefs = SkippedRefsLink
+	}
+	if o.Reactions == "" {
+		o.Reactions = ReactionsDrop
+	}
+	if o.TextAlign == "" {
+		o.TextAlign = AlignStrip
+	}
+	if o.MaxRefDepth == 0 {
+		o.MaxRefDepth = 10
+	}
+	if o.NestedHeadings == "" {
+		o.NestedHeadings = HeadingBold
+	}
+	if o.BulletMarker == "" {
+		o.BulletMarker = "*"
+		if o.Style != StyleIndent {
+			o.BulletMarker = "-"
+		}
+	}
+}
+
+// Validate checks that every option has a known value.
+func (o Options) Validate() error {
+	o.setDefaults()
+
+	switch o.Target {
+	case TargetObsidian, TargetLogseq, TargetHTML:
+	default:
+		return fmt.Errorf("unknown target %q", o.Target)
+	}
+
+	if o.Target != TargetObsidian && o.DailyNotesConfig {
+		return fmt.Errorf("daily notes config is not supported by the %s target", o.Target)
+	}
+
+	switch o.MOC {
+	case "", MOCLetter, MOCNamespace:
+	default:
+		return fmt.Errorf("unknown index note grouping %q", o.MOC)
+	}
+
+	if o.Target != TargetObsidian && o.MOC != "" {
+		return fmt.Errorf("index notes are not supported by the %s target", o.Target)
+	}
+
+	if o.Target != TargetObsidian && o.InitVault {
+		return fmt.Errorf("vault settings are not supported by the %s target", o.Target)
+	}
+
+	if o.Target != TargetObsidian && o.RoamCodePages {
+		return fmt.Errorf("roam/css snippets are not supported by the %s target", o.Target)
+	}
+
+	if o.Target != TargetObsidian && o.Anki {
+		return fmt.Errorf("anki flashcards are not supported by the %s target", o.Target)
+	}
+
+	if o.Target != TargetObsidian && o.Templates {
+		return fmt.Errorf("templates are not supported by the %s target", o.Target)
+	}
+
+	if o.Target != TargetObsidian && len(o.CanvasFor) > 0 {
+		return fmt.Errorf("canvases are not supported by the %s target", o.Target)
+	}
+
+	if o.Target == TargetLogseq && o.AuthorCallouts {
+		return fmt.Errorf("author callouts are not supported by the %s target", o.Target)
+	}
+
+	if o.Target == TargetHTML && o.MobileSafe {
+		return fmt.Errorf("mobile safe mode is not supported by the %s target", o.Target)
+	}
+
+	switch o.AnnotationStyle {
+	case AnnotationFootnote, AnnotationCallout:
+	default:
+		return fmt.Errorf("unknown annotation style %q", o.AnnotationStyle)
+	}
+
+	switch o.HiccupFallback {
+	case HiccupPassthrough, HiccupStrip:
+	default:
+		return fmt.Errorf("unknown hiccup fallback %q", o.HiccupFallback)
+	}
+
+	for _, strategy := range o.Shard {
+		if !containsString(ShardStrategies, strategy) {
+			return fmt.Errorf("unknown shard strategy %q", strategy)
+		}
+		if o.Target != TargetObsidian && strategy != ShardPruneEmpty {
+			return fmt.Errorf("shard strategy %q is not supported by the %s target", strategy, o.Target)
+		}
+	}
+
+	switch o.CodeWrappers {
+	case WrapperFence, WrapperSnippet:
+	default:
+		return fmt.Errorf("unknown code wrapper mode %q", o.CodeWrappers)
+	}
+
+	switch o.Style {
+	case StyleIndent, StyleOutline, StyleProse:
+	default:
+		return fmt.Errorf("unknown style %q", o.Style)
+	}
+
+	switch o.BulletMarker {
+	case "-", "*", "+", BulletNumbered:
+	default:
+		return fmt.Errorf("unknown bullet marker %q", o.BulletMarker)
+	}
+
+	if o.Indent != "" && o.Indent != IndentTab {
+		if n, err := strconv.Atoi(o.Indent); err != nil || n < 1 || n > 8 {
+			return fmt.Errorf("indent must be %s or 1 to 8 spaces, not %q", IndentTab, o.Indent)
+		}
+	}
+
+	switch o.BareDates {
+	case "", BareDatesText, BareDatesLink:
+	default:
+		return fmt.Errorf("unknown bare dates mode %q", o.BareDates)
+	}
+
+	switch o.SkippedRefs {
+	case SkippedRefsLink, SkippedRefsText:
+	default:
+		return fmt.Errorf("unknown skipped refs mode %q", o.SkippedRefs)
+	}
+
+	if !o.DailyFrom.IsZero() && !o.DailyTo.IsZero() && o.DailyTo.Before(o.DailyFrom) {
+		return fmt.Errorf("daily range ends before it starts")
+	}
+
+	switch o.Reactions {
+	case ReactionsDrop:
+	case ReactionsComment, ReactionsFrontmatter:
+		if o.Target != TargetObsidian {
+			return fmt.Errorf("reactions are not supported by the %s target", o.Target)
+		}
+	default:
+		return fmt.Errorf("unknown reactions mode %q", o.Reactions)
+	}
+
+	switch o.TextAlign {
+	case AlignStrip, AlignHTML:
+	default:
+		return fmt.Errorf("unknown text align mode %q", o.TextAlign)
+	}
+
+	if o.MobileSafe && o.TextAlign == AlignHTML {
+		return fmt.Errorf("mobile safe mode writes no HTML, so text alignment can't be kept")
+	}
+
+	switch o.Iframes {
+	case IframeHTML, IframeSandbox, IframeLink:
+	default:
+		return fmt.Errorf("unknown iframe mode %q", o.Iframes)
+	}
+
+	if o.MobileSafe && o.Iframes != IframeLink {
+		return fmt.Errorf("mobile safe mode writes no iframes, so they can only be links")
+	}
+
+	if o.MaxRefDepth < 0 {
+		return fmt.Errorf("max ref depth must not be negative")
+	}
+
+	switch o.NestedHeadings {
+	case HeadingBold, HeadingKeep:
+	default:
+		return fmt.Errorf("unknown nested heading mode %q", o.NestedHeadings)
+	}
+
+	switch o.FolderIndex {
+	case "", FolderIndexReadme, FolderIndexAbout:
+	default:
+		return fmt.Errorf("unknown folder index %q", o.FolderIndex)
+	}
+
+	switch o.DayStyle {
+	case DayStyleObsidian, DayStyleRoam:
+	default:
+		return fmt.Errorf("unknown day style %q", o.DayStyle)
+	}
+
+	switch o.Queries {
+	case QueryCallout, QueryKeep, QueryStrip:
+	default:
+		return fmt.Errorf("unknown query block mode %q", o.Queries)
+	}
+
+	switch o.SmartBlocks {
+	case SmartBlocksKeep, SmartBlocksStrip, SmartBlocksText:
+	default:
+		return fmt.Errorf("unknown SmartBlocks mode %q", o.SmartBlocks)
+	}
+
+	switch o.MissingRefs {
+	case MissingRefsPlaceholder, MissingRefsKeep:
+	default:
+		return fmt.Errorf("unknown missing refs mode %q", o.MissingRefs)
+	}
+
+	switch o.PageTags {
+	case "", PageTagsCopy, PageTagsMove:
+	default:
+		return fmt.Errorf("unknown page tags mode %q", o.PageTags)
+	}
+
+	switch o.Quotes {
+	case QuotesPlain, QuotesCallout:
+	default:
+		return fmt.Errorf("unknown quote mode %q", o.Quotes)
+	}
+
+	if o.Target == TargetLogseq && o.Quotes == QuotesCallout {
+		return fmt.Errorf("quote callouts are not supported by the %s target", o.Target)
+	}
+
+	switch o.SlashTags {
+	case SlashTagsTag, SlashTagsLink, SlashTagsBoth:
+	default:
+		return fmt.Errorf("unknown slash tag mode %q", o.SlashTags)
+	}
+
+	switch o.SRS {
+	case SRSKeep, SRSFlashcards:
+	default:
+		return fmt.Errorf("unknown spaced repetition mode %q", o.SRS)
+	}
+
+	if o.Target != TargetObsidian && o.SRS == SRSFlashcards {
+		return fmt.Errorf("flashcards are not supported by the %s target", o.Target)
+	}
+
+	switch o.Tasks {
+	case TasksKeep, TasksPlugin:
+	default:
+		return fmt.Errorf("unknown tasks mode %q", o.Tasks)
+	}
+
+	if o.Target != TargetObsidian && o.Tasks == TasksPlugin {
+		return fmt.Errorf("tasks plugin syntax is not supported by the %s target", o.Target)
+	}
+
+	if err := validateWidgets(o.Widgets); err != nil {
+		return err
+	}
+
+	// rules built by hand rather than with NewReplacement or LoadRules are
+	// compiled here
+	for _, r := range o.Replacements {
+		if r.re == nil {
+			if err := r.Compile(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if o.MobileSafe {
+		for name, mode := range o.Widgets {
+			if mode == WidgetHTML {
+				return fmt.Errorf("mobile safe mode writes no HTML, so widget %q can't be HTML", name)
+			}
+		}
+	}
+
+	switch o.Encrypted {
+	case EncryptedStrip, EncryptedCallout:
+	case EncryptedDecrypt:
+		if o.EncryptionPassword == "" {
+			return fmt.Errorf("decrypting blocks needs the Roam encryption password")
+		}
+	default:
+		return fmt.Errorf("unknown encrypted blocks mode %q", o.Encrypted)
+	}
+
+	switch o.OnError {
+	case OnErrorFail, OnErrorWarn, OnErrorSkip:
+	default:
+		return fmt.Errorf("unknown error policy %q", o.OnError)
+	}
+
+	switch o.OnCollision {
+	case CollisionWarn, CollisionError, CollisionSuffix:
+	case CollisionMerge:
+		if o.Target != TargetObsidian {
+			return fmt.Errorf("merging colliding pages is not supported by the %s target", o.Target)
+		}
+	default:
+		return fmt.Errorf("unknown collision policy %q", o.OnCollision)
+	}
+
+	if o.Resume && o.DryRun {
+		return fmt.Errorf("resume can't be combined with a dry run")
+	}
+
+	if o.Strict && o.OnError != OnErrorFail {
+		return fmt.Errorf("strict mode can't be combined with the %s error policy", o.OnError)
+	}
+
+	switch o.RenderPolicy {
+	case RenderStrip, RenderCallout, RenderReport:
+	default:
+		return fmt.Errorf("unknown roam/render policy %q", o.RenderPolicy)
+	}
+
+	return nil
+}
+
+// Converter converts a Roam export into an Obsidian vault. A Converter holds
+// the state of a single conversion and should not be reused.
+type Converter struct {
+	opts Options
+	log  Logger
+	w    vault.Writer
+
+	uidBlock      map[string]*roam.Child
+	referencedUID map[string]struct{}
+	uidMap        UIDMap
+	roamTitles    []string
+	starred       []starredBlock
+	refChains     map[string]struct{}
+	refText       map[string]string
+	embedding     map[string]struct{}
+	attrs         *attrIndex
+	pageReactions map[string][]reactionCount
+	excluded      map[string]struct{}
+	backlinks     map[string][]backlink
+
+	// page is the page being expanded, and pageSkipped whether it is not
+	// written.
+	page        *roam.Page
+	pageSkipped bool
+	// templates is the roam/templates page, when its templates are written
+	// to the templates folder.
+	templates *roam.Page
+
+	contacts map[string]string
+
+	renderUses map[string]renderUse
+
+	quarantined map[int]error
+
+	report *Report
+
+	// renamed maps page titles to the filename they were given to avoid a
+	// collision.
+	renamed map[string]string
+	// bracketed lists the titles with [[links]] in them, longest first.
+	bracketed []string
+	// collided maps the titles of pages merged after a collision to the
+	// first of them, whose file they are written to.
+	collided         map[string]string
+	collidedSections map[string][]mergedSection
+	collidedOrder    []string
+
+	// dailyDates maps daily note titles to their date.
+	dailyDates map[string]time.Time
+
+	// aliases maps page titles to the aliases written to their frontmatter.
+	aliases map[string][]string
+	// pageTags maps page titles to the tags written to their frontmatter.
+	pageTags map[string][]string
+
+	// skipped maps the index of pages that aren't written to the reason.
+	skipped map[int]string
+
+	// merged maps daily note titles to the merged note they are written to.
+	merged         map[string]string
+	mergedSections map[string][]mergedSection
+
+	// snippets maps snippet file paths to their contents.
+	snippets map[string]string
+	// ankiUIDs are the blocks tagged as flashcards, ankiCards the cards
+	// written from them.
+	ankiUIDs  map[string]struct{}
+	ankiCards []ankiCard
+	// drawings maps Excalidraw file paths to their contents.
+	drawings map[string]string
+
+	// checkpoint records the pages written by this run, resumed the pages
+	// written by the run it resumes.
+	checkpoint checkpoint
+	resumed    map[string]checkpointPage
+
+	unresolved map[UnresolvedRef]struct{}
+
+	// smartBlockUIDs are the blocks already listed in the report for their
+	// SmartBlocks syntax.
+	smartBlockUIDs map[string]struct{}
+
+	// replaced counts the blocks changed by replacements at each stage.
+	replaced map[string]int
+
+	// writing is set while pages are written.
+	writing bool
+
+	stats Stats
+
+	// notes lists the notes written to the vault.
+	notes []writtenNote
+
+	// htmlFiles maps file titles to their HTML file for the HTML target.
+	htmlFiles map[string]string
+}
+
+// New creates a Converter.
+func New(opts Options) (*Converter, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts.setDefaults()
+
+	c := &Converter{
+		opts:             opts,
+		uidBlock:         map[string]*roam.Child{},
+		referencedUID:    map[string]struct{}{},
+		uidMap:           UIDMap{Pages: map[string]string{}, Blocks: map[string]string{}},
+		refChains:        map[string]struct{}{},
+		refText:          map[string]string{},
+		embedding:        map[string]struct{}{},
+		pageReactions:    map[string][]reactionCount{},
+		excluded:         map[string]struct{}{},
+		backlinks:        map[string][]backlink{},
+		contacts:         map[string]string{},
+		renderUses:       map[string]renderUse{},
+		quarantined:      map[int]error{},
+		report:           &Report{},
+		renamed:          map[string]string{},
+		collided:         map[string]string{},
+		collidedSections: map[string][]mergedSection{},
+		aliases:          map[string][]string{},
+		pageTags:         map[string][]string{},
+		dailyDates:       map[string]time.Time{},
+		htmlFiles:        map[string]string{},
+		skipped:          map[int]string{},
+		merged:           map[string]string{},
+		mergedSections:   map[string][]mergedSection{},
+		snippets:         map[string]string{},
+		ankiUIDs:         map[string]struct{}{},
+		checkpoint:       checkpoint{Pages: map[string]checkpointPage{}},
+		drawings:         map[string]string{},
+		unresolved:       map[UnresolvedRef]struct{}{},
+		smartBlockUIDs:   map[string]struct{}{},
+		replaced:         map[string]int{},
+	}
+	c.log = countingLogger{Logger: opts.Logger, warnings: &c.stats.Warnings}
+
+	return c, nil
+}
+
+// Report returns the problems found by Convert.
+func (c *Converter) Report() *Report {
+	return c.report
+}
+
+// Stats returns the statistics of the conversion.
+func (c *Converter) Stats() Stats {
+	return c.stats
+}
+
+// Notes returns the vault paths of the notes written for pages, in the order
+// they were written.
+func (c *Converter) Notes() []string {
+	paths := make([]string, len(c.notes))
+	for i, note := range c.notes {
+		paths[i] = note.path
+	}
+
+	return paths
+}
+
+// Convert converts pages and writes the notes to w.
+func (c *Converter) Convert(pages []roam.Page, w vault.Writer) error {
+	c.w = w
+	if c.opts.DryRun {
+		c.w = vault.Discard
+	}
+
+	if c.opts.Resume {
+		if err := c.loadCheckpoint(); err != nil {
+			return err
+		}
+	}
+
+	if c.opts.UIDMap {
+		c.recordTitles(pages)
+	}
+
+	if err := c.pass1(pages); err != nil {
+		return fmt.Errorf("pass1: %w", err)
+	}
+
+	c.collectStarred(pages)
+	if c.opts.Anki {
+		c.collectAnkiBlocks(pages)
+	}
+
+	c.stripTitleBrackets(pages)
+	if c.opts.StripTitleEmoji {
+		c.stripTitleEmoji(pages)
+	}
+	if c.opts.Slug {
+		c.slugTitles(pages)
+	}
+	if c.opts.MobileSafe {
+		c.mobileTitles(pages)
+	}
+	if err := c.detectCollisions(pages, c.opts.OnCollision); err != nil {
+		return err
+	}
+	if c.opts.RoamCodePages {
+		c.extractRoamCode(pages)
+	}
+	if c.opts.Templates {
+		c.skipTemplatesPage(pages)
+	}
+	c.excludePages(pages, c.opts.Exclude)
+	c.includePages(pages, c.opts.Include, c.opts.IncludeLinked)
+	if err := c.selectPages(pages, c.opts.Pages); err != nil {
+		return err
+	}
+	c.skipDailyRange(pages, c.opts.DailyFrom, c.opts.DailyTo)
+	c.skipPages(pages, c.opts.SkipEmpty, c.opts.SkipOrphans)
+	c.shard(pages, c.opts.MaxFiles, c.opts.Shard)
+
+	if c.opts.BacklinksSection {
+		c.collectBacklinks(pages)
+	}
+
+	if err := c.pass2(pages); err != nil {
+		return fmt.Errorf("pass2: %w", err)
+	}
+
+	if c.opts.UIDMap {
+		c.anchorAllBlocks()
+	}
+
+	if c.opts.Strict {
+		if err := c.report.strictError(); err != nil {
+			return err
+		}
+	}
+
+	if c.opts.Target == TargetHTML {
+		c.htmlPaths(pages)
+	}
+
+	if err := c.pass3(pages); err != nil {
+		return err
+	}
+
+	if c.opts.Target == TargetHTML {
+		if err := c.writeHTMLIndex(pages); err != nil {
+			return fmt.Errorf("write index: %w", err)
+		}
+	}
+
+	c.stats.Skipped = len(c.skipped)
+	c.stats.Quarantined = len(c.quarantined)
+	if c.opts.OnError == OnErrorSkip {
+		c.stats.Skipped += len(c.quarantined)
+		c.stats.Quarantined = 0
+	}
+
+	if len(c.opts.CanvasFor) > 0 {
+		if err := c.writeCanvases(pages); err != nil {
+			return err
+		}
+	}
+
+	if c.opts.LinkEmails {
+		if err := c.writeContactPages(pages); err != nil {
+			return err
+		}
+	}
+
+	if err := c.writeManualMigrationPage(); err != nil {
+		return err
+	}
+
+	if c.opts.Target != TargetHTML {
+		if err := c.writeStarred(pages); err != nil {
+			return fmt.Errorf("write starred: %w", err)
+		}
+	}
+
+	if err := c.writeTemplates(); err != nil {
+		return fmt.Errorf("write templates: %w", err)
+	}
+
+	if err := c.writeSnippets(); err != nil {
+		return fmt.Errorf("write snippets: %w", err)
+	}
+
+	if err := c.writeAnkiCards(); err != nil {
+		return fmt.Errorf("write flashcards: %w", err)
+	}
+
+	if err := c.writeDrawings(); err != nil {
+		return fmt.Errorf("write drawings: %w", err)
+	}
+
+	if err := c.writeQuarantine(pages); err != nil {
+		return fmt.Errorf("write quarantine: %w", err)
+	}
+
+	if c.opts.UIDMap {
+		if err := c.writeUIDMap(); err != nil {
+			return fmt.Errorf("write uid map: %w", err)
+		}
+	}
+
+	if c.opts.MOC != "" {
+		if err := c.writeMOCs(pages); err != nil {
+			return fmt.Errorf("write index notes: %w", err)
+		}
+	}
+
+	switch {
+	case c.opts.InitVault:
+		if err := c.initVault(); err != nil {
+			return fmt.Errorf("init vault: %w", err)
+		}
+	case c.opts.DailyNotesConfig && c.stats.DailyNotes > 0:
+		if err := c.writeDailyNotesConfig(); err != nil {
+			return fmt.Errorf("write daily notes config: %w", err)
+		}
+	}
+
+	if c.opts.FolderIndex != "" {
+		if err := c.writeFolderIndexes(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.report.write(c.w); err != nil {
+		return err
+	}
+
+	return c.removeCheckpoint()
+}
+
+func (c *Converter) pass1(pages []roam.Page) error {
+	c.log.Info("pass 1: scan all pages", "pages", len(pages))
+	bar := c.opts.Progress
+	bar.Start("scan", len(pages))
+
+	for i := range pages {
+		err := c.safely(i, func() error {
+			if err := c.parsePageDate(&pages[i]); err != nil {
+				return fmt.Errorf("parse page date: %w", err)
+			}
+
+			c.applyReplacements(&pages[i], pages[i].RawChildren)
+			c.collectAliases(&pages[i], c.opts.KeepAliasBlocks)
+			if c.opts.PageTags != "" {
+				c.collectPageTags(&pages[i], c.opts.PageTags)
+			}
+
+			// collect uid
+			collectBlocks(c.uidBlock, &pages[i], pages[i].RawChildren)
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		bar.Increment()
+	}
+
+	bar.Finish()
+
+	if len(c.opts.Replacements) > 0 {
+		c.log.Info("replaced block text", "blocks", c.replaced[StageSource])
+	}
+
+	return nil
+}
+
+func (c *Converter) pass2(pages []roam.Page) error {
+	c.log.Info("pass 2: track block references")
+
+	bar := c.opts.Progress
+	bar.Start("track", len(pages))
+	for i, page := range pages {
+		if _, ok := c.quarantined[i]; ok {
+			continue
+		}
+
+		c.page = &page
+		_, c.pageSkipped = c.skipped[i]
+		err := c.safely(i, func() error {
+			_, err := c.expandChildren(&page, 0)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("pass2: %w", err)
+		}
+		bar.Increment()
+	}
+	c.pageSkipped = false
+	bar.Finish()
+
+	return nil
+}
+
+// noteDate returns the date of the note written for page.
+func (c *Converter) noteDate(page *roam.Page) time.Time {
+	if page.IsDaily {
+		return c.dailyDates[page.Title]
+	}
+
+	return c.localTime(page.CreateTime)
+}
+
+func (c *Converter) pass3(pages []roam.Page) error {
+	c.log.Info("pass 3: write pages")
+	c.writing = true
+
+	bar := c.opts.Progress
+	bar.Start("write", len(pages))
+	for i, page := range pages {
+		if _, ok := c.quarantined[i]; ok || page.Title == "" {
+			continue
+		}
+
+		if _, ok := c.skipped[i]; ok {
+			continue
+		}
+
+		dest := c.pagePath(&page) + ".md"
+		if c.opts.Target == TargetHTML {
+			dest = c.pagePath(&page) + ".html"
+		}
+
+		c.log.Debug("write page", "page", page.Title, "path", dest)
+		if c.opts.UIDMap {
+			c.mapPage(i, &page, dest)
+		}
+
+		resumed, err := c.resumePage(&page, dest)
+		if err != nil {
+			return err
+		}
+		if resumed {
+			bar.Increment()
+			continue
+		}
+
+		c.page = &page
+		before, deferred := c.stats, c.deferredOutputs()
+		written := false
+		err = c.safely(i, func() error {
+			lines, err := c.expandPage(&page)
+			if err != nil {
+				return err
+			}
+
+			if c.opts.BacklinksSection {
+				lines = append(lines, c.backlinksSection(&page)...)
+			}
+
+			if c.opts.AnnotationStyle == AnnotationFootnote {
+				if footnotes := c.annotationFootnotes(&page); len(footnotes) > 0 {
+					lines = append(lines, "")
+					lines = append(lines, footnotes...)
+				}
+			}
+
+			c.stats.Pages++
+			if page.IsDaily {
+				c.stats.DailyNotes++
+			}
+
+			if _, ok := c.merged[page.Title]; ok {
+				c.addMergedSection(page.Title, lines)
+				return nil
+			}
+			if _, ok := c.collided[page.Title]; ok {
+				c.addCollidedSection(&page, dest, lines)
+				return nil
+			}
+
+			lines = append(c.frontmatter(&page), lines...)
+			data := []byte(strings.Join(lines, "\n"))
+
+			if c.opts.Target == TargetHTML {
+				if data, err = c.renderHTML(page.Title, dest, lines); err != nil {
+					return err
+				}
+			}
+
+			if err := c.w.WriteFile(dest, data); err != nil {
+				return err
+			}
+			c.addNote(dest, c.noteDate(&page))
+			written = true
+
+			return nil
+		})
+		if err != nil {
+			if cerr := c.saveCheckpoint(); cerr != nil {
+				c.log.Warn("save checkpoint", "error", cerr.Error())
+			}
+			return err
+		}
+		if written {
+			if err := c.recordPage(&page, dest, before, deferred); err != nil {
+				return err
+			}
+		}
+
+		bar.Increment()
+	}
+	bar.Finish()
+
+	if len(c.opts.Replacements) > 0 {
+		c.log.Info("replaced converted text", "blocks", c.replaced[StageMarkdown])
+	}
+
+	if err := c.writeCollided(); err != nil {
+		return err
+	}
+
+	return c.writeMerged()
+}
@@ -0,0 +1,179 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	// DayStyleObsidian names daily notes 2006-01-02.
+	DayStyleObsidian = "obsidian"
+	// DayStyleRoam keeps Roam's "January 2nd, 2006" names.
+	DayStyleRoam = "roam"
+
+	obsDailyLayout = "2006-01-02"
+)
+
+const (
+	// BareDatesText rewrites dates in plain text to the daily note title.
+	BareDatesText = "text"
+	// BareDatesLink also links them to the daily note.
+	BareDatesLink = "link"
+)
+
+// formatDaily formats t as a daily note title in the configured style.
+func (c *Converter) formatDaily(t time.Time) string {
+	if c.logseq() {
+		return logseqDailyTitle(t)
+	}
+
+	if c.opts.DayStyle == DayStyleRoam {
+		return roam.FormatDate(t)
+	}
+
+	return t.Format(obsDailyLayout)
+}
+
+// parsePageDate renames daily note pages and marks them as daily.
+func (c *Converter) parsePageDate(page *roam.Page) error {
+	t, ok, err := c.parseDailyTitle(page.Title)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		page.Title = c.formatDaily(t)
+		page.IsDaily = true
+		c.dailyDates[page.Title] = t
+	}
+
+	return nil
+}
+
+// localTime returns the Roam timestamp t in the time zone set in the
+// options.
+func (c *Converter) localTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+
+	return t.In(c.opts.Location)
+}
+
+// replaceDayLinks rewrites links to daily notes to the daily note title.
+func (c *Converter) replaceDayLinks(in string) (string, error) {
+	in, err := c.replacePatternDayLinks(in)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	last := 0
+
+	for _, match := range reDayLink.FindAllStringSubmatchIndex(in, -1) {
+		date := in[match[2]:match[3]]
+		t, _, err := roam.ParseDate(date)
+		if err != nil {
+			return "", fmt.Errorf("invalid date %q: %w", date, err)
+		}
+
+		sb.WriteString(in[last:match[0]])
+		sb.WriteString("[[" + c.formatDaily(t) + "]]")
+		last = match[1]
+	}
+	sb.WriteString(in[last:])
+
+	return sb.String(), nil
+}
+
+// replacePatternDayLinks rewrites links to daily notes whose titles match a
+// configured daily pattern.
+func (c *Converter) replacePatternDayLinks(in string) (string, error) {
+	if len(c.opts.DailyPatterns) == 0 || !strings.Contains(in, "[[") {
+		return in, nil
+	}
+
+	var sb strings.Builder
+	last := 0
+
+	for _, match := range reWikiLink.FindAllStringSubmatchIndex(in, -1) {
+		target := in[match[2]:match[3]]
+		for _, p := range c.opts.DailyPatterns {
+			t, ok, err := p.Match(target)
+			if !ok {
+				continue
+			}
+			if err != nil {
+				return "", fmt.Errorf("invalid date %q: %w", target, err)
+			}
+
+			sb.WriteString(in[last:match[0]])
+			sb.WriteString("[[" + c.formatDaily(t) + "]]")
+			last = match[1]
+			break
+		}
+	}
+	sb.WriteString(in[last:])
+
+	return sb.String(), nil
+}
+
+// replaceBareDates rewrites dates written as plain text in the Roam daily
+// title format to the daily note title, as a link in BareDatesLink mode.
+// Dates in links and code are left alone.
+func (c *Converter) replaceBareDates(in string) string {
+	var sb strings.Builder
+	last := 0
+
+	for _, m := range reBareDateSkip.FindAllStringIndex(in, -1) {
+		sb.WriteString(c.replaceBareDateText(in[last:m[0]]))
+		sb.WriteString(in[m[0]:m[1]])
+		last = m[1]
+	}
+	sb.WriteString(c.replaceBareDateText(in[last:]))
+
+	return sb.String()
+}
+
+func (c *Converter) replaceBareDateText(s string) string {
+	return reBareDate.ReplaceAllStringFunc(s, func(date string) string {
+		t, ok, err := roam.ParseDate(date)
+		if !ok || err != nil {
+			return date
+		}
+
+		title := c.formatDaily(t)
+		if c.opts.BareDates == BareDatesLink {
+			return "[[" + title + "]]"
+		}
+
+		return title
+	})
+}
+
+// parseDailyTitle parses title as a Roam daily note title or, failing that,
+// with the configured daily patterns.
+func (c *Converter) parseDailyTitle(title string) (time.Time, bool, error) {
+	if t, ok, err := roam.ParseDate(title); ok {
+		return t, ok, err
+	}
+
+	for _, p := range c.opts.DailyPatterns {
+		if t, ok, err := p.Match(title); ok {
+			return t, ok, err
+		}
+	}
+
+	return time.Time{}, false, nil
+}
+
+var (
+	reBareDate     = regexp.MustCompile(`\b` + roam.DatePattern + `\b`)
+	reBareDateSkip = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`|\\[\\[[^\\[\\]]*\\]\\]|\\[[^\\]]*\\]\\([^)]*\\)")
+)
+
+var reDayLink = regexp.MustCompile(`\[\[(` + roam.DatePattern + `)\]\]`)
@@ -0,0 +1,277 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+const drawingDir = "drawings"
+
+// excalidrawScene is the drawing data of an Excalidraw file.
+type excalidrawScene struct {
+	Type     string                   `json:"type"`
+	Version  int                      `json:"version"`
+	Source   string                   `json:"source"`
+	Elements []map[string]interface{} `json:"elements"`
+	AppState map[string]interface{}   `json:"appState"`
+	Files    map[string]interface{}   `json:"files"`
+}
+
+// drawing writes the {{drawing}} or {{diagram}} in a block as an Excalidraw
+// file and embeds it. Freehand lines become freedraw elements and the blocks
+// of a diagram become text. When the lines can't be read, the Roam data is
+// kept next to an empty drawing so it isn't lost.
+func (c *Converter) drawing(call componentCall) (string, bool) {
+	if c.opts.Target != TargetObsidian {
+		return "", false
+	}
+
+	block, ok := c.uidBlock[call.BlockUID]
+	if !ok {
+		return "", false
+	}
+
+	var elements []map[string]interface{}
+	var texts []string
+	lines, linesOK := drawingLines(block.Props)
+	for i, line := range lines {
+		elements = append(elements, freedrawElement(block.UID, i, line))
+	}
+	if strings.EqualFold(call.Name, "diagram") {
+		for i, child := range block.Children() {
+			elements = append(elements, textElement(child.UID, child.String, 0, float64(i*60)))
+			texts = append(texts, child.String+" ^"+child.UID, "")
+		}
+	}
+
+	if len(block.Props) == 0 && len(elements) == 0 {
+		return "*(Roam drawing not included in the export)*", true
+	}
+
+	name := fmt.Sprintf("%s-%s", strings.ReplaceAll(c.fileTitle(c.page.Title), "/", "-"), block.UID)
+	rel := drawingDir + "/" + name + ".excalidraw.md"
+
+	scene := excalidrawScene{
+		Type:     "excalidraw",
+		Version:  2,
+		Source:   "goram2obs",
+		Elements: elements,
+		AppState: map[string]interface{}{"viewBackgroundColor": "#ffffff"},
+		Files:    map[string]interface{}{},
+	}
+	if scene.Elements == nil {
+		scene.Elements = []map[string]interface{}{}
+	}
+	data, err := json.MarshalIndent(scene, "", "  ")
+	if err != nil {
+		return "", false
+	}
+
+	c.drawings[rel] = excalidrawNote(texts, data)
+	if len(block.Props) > 0 && !linesOK {
+		if c.writing {
+			c.log.Warn("drawing data not understood, keeping it as JSON", "page", c.page.Title, "block", block.UID)
+		}
+		c.drawings[drawingDir+"/"+name+".roam.json"] = string(block.Props) + "\n"
+	}
+
+	return "![[" + strings.TrimSuffix(rel, ".md") + "]]", true
+}
+
+// excalidrawNote wraps a scene in the Markdown file the Excalidraw plugin
+// reads. texts lists the text elements, which the plugin also keeps in the
+// Markdown.
+func excalidrawNote(texts []string, scene []byte) string {
+	lines := []string{
+		"---",
+		"excalidraw-plugin: parsed",
+		"tags: [excalidraw]",
+		"---",
+		"==⚠  Switch to EXCALIDRAW VIEW in the MORE OPTIONS menu of this document. ⚠==",
+		"",
+		"# Text Elements",
+	}
+	lines = append(lines, texts...)
+	lines = append(lines,
+		"%%",
+		"# Drawing",
+		"```json",
+		string(scene),
+		"```",
+		"%%",
+		"",
+	)
+
+	return strings.Join(lines, "\n")
+}
+
+// drawingLine is a freehand stroke.
+type drawingLine struct {
+	points [][2]float64
+	color  string
+}
+
+// drawingLines reads the strokes from a block's props. Roam has written the
+// keys both plainly and as keywords (:drawing/lines), and points both as
+// pairs and as objects. It reports false if props hold data but no strokes
+// could be read from it.
+func drawingLines(props json.RawMessage) ([]drawingLine, bool) {
+	if len(props) == 0 {
+		return nil, true
+	}
+
+	drawing := propValue(props, "drawing")
+	if drawing == nil {
+		drawing = props
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(propValue(drawing, "lines"), &raw); err != nil {
+		return nil, false
+	}
+
+	var lines []drawingLine
+	for _, r := range raw {
+		var line drawingLine
+		if err := json.Unmarshal(propValue(r, "color"), &line.color); err != nil {
+			line.color = "#000000"
+		}
+
+		var points []json.RawMessage
+		if err := json.Unmarshal(propValue(r, "points"), &points); err != nil {
+			return nil, false
+		}
+		for _, p := range points {
+			var pair [2]float64
+			if err := json.Unmarshal(p, &pair); err != nil {
+				var xy struct{ X, Y float64 }
+				if err := json.Unmarshal(p, &xy); err != nil {
+					return nil, false
+				}
+				pair = [2]float64{xy.X, xy.Y}
+			}
+			line.points = append(line.points, pair)
+		}
+
+		if len(line.points) > 0 {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, true
+}
+
+// propValue returns the value of the key name in the JSON object raw, where
+// the key may be a keyword with a namespace such as :drawing/name.
+func propValue(raw json.RawMessage, name string) json.RawMessage {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		k := strings.TrimPrefix(key, ":")
+		if i := strings.LastIndex(k, "/"); i >= 0 {
+			k = k[i+1:]
+		}
+		if k == name {
+			return m[key]
+		}
+	}
+
+	return nil
+}
+
+func freedrawElement(uid string, i int, line drawingLine) map[string]interface{} {
+	x0, y0 := line.points[0][0], line.points[0][1]
+	maxX, maxY := 0.0, 0.0
+	points := make([][2]float64, len(line.points))
+	for j, p := range line.points {
+		points[j] = [2]float64{p[0] - x0, p[1] - y0}
+		if points[j][0] > maxX {
+			maxX = points[j][0]
+		}
+		if points[j][1] > maxY {
+			maxY = points[j][1]
+		}
+	}
+
+	el := baseElement(fmt.Sprintf("%s-%d", uid, i), "freedraw", x0, y0)
+	el["width"], el["height"] = maxX, maxY
+	el["strokeColor"] = line.color
+	el["points"] = points
+	el["pressures"] = []float64{}
+	el["simulatePressure"] = true
+
+	return el
+}
+
+func textElement(uid, text string, x, y float64) map[string]interface{} {
+	el := baseElement(uid, "text", x, y)
+	el["text"] = text
+	el["originalText"] = text
+	el["fontSize"] = 20
+	el["fontFamily"] = 1
+	el["textAlign"] = "left"
+	el["verticalAlign"] = "top"
+	el["width"] = float64(len([]rune(text)) * 11)
+	el["height"] = 25.0
+
+	return el
+}
+
+func baseElement(id, kind string, x, y float64) map[string]interface{} {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	seed := int(h.Sum32() & 0x7fffffff)
+
+	return map[string]interface{}{
+		"id":              id,
+		"type":            kind,
+		"x":               x,
+		"y":               y,
+		"angle":           0,
+		"strokeColor":     "#000000",
+		"backgroundColor": "transparent",
+		"fillStyle":       "solid",
+		"strokeWidth":     1,
+		"strokeStyle":     "solid",
+		"roughness":       0,
+		"opacity":         100,
+		"groupIds":        []string{},
+		"seed":            seed,
+		"version":         1,
+		"versionNonce":    seed,
+		"isDeleted":       false,
+		"boundElements":   nil,
+		"link":            nil,
+		"locked":          false,
+	}
+}
+
+// writeDrawings writes the Excalidraw files of the converted drawings.
+func (c *Converter) writeDrawings() error {
+	paths := make([]string, 0, len(c.drawings))
+	for p := range c.drawings {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if err := c.w.WriteFile(p, []byte(c.drawings[p])); err != nil {
+			return err
+		}
+		c.stats.Assets++
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package convert
+
+import (
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// Embed variants besides {{embed}}.
+const (
+	// embedChildren embeds the children of a block without the block.
+	embedChildren = "embed-children"
+	// embedPath embeds a block below the blocks it is nested in.
+	embedPath = "embed-path"
+)
+
+// embeddedChildren returns the children of block, expanded, for an
+// {{embed-children}}. Each child goes on a line of its own, nested below
+// the embedding block. The copies aren't counted in the stats.
+func (c *Converter) embeddedChildren(block *roam.Child) (string, error) {
+	c.embedding[block.UID] = struct{}{}
+	writing := c.writing
+	c.writing = false
+	defer func() {
+		delete(c.embedding, block.UID)
+		c.writing = writing
+	}()
+
+	// the lines are indented like the embedding block's own, so outside
+	// an outline they start a level deeper to be nested below it
+	level := 0
+	if layout, _ := c.blockLayout(0); layout != layoutOutline {
+		level = 1
+	}
+
+	lines, err := c.expandChildren(block, level)
+	if err != nil || len(lines) == 0 {
+		return "", err
+	}
+
+	return "\n" + strings.TrimRight(strings.Join(lines, "\n"), "\n"), nil
+}
+
+// embedsItself reports whether an embed of block would contain the blocks
+// in path, the chain of blocks being expanded, because they are among the
+// children it embeds.
+func (c *Converter) embedsItself(kind string, block *roam.Child, path []string) bool {
+	if _, ok := c.embedding[block.UID]; ok {
+		return true
+	}
+	if kind != embedChildren {
+		return false
+	}
+
+	for _, uid := range path {
+		if blockAncestors(block, uid) != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// embedPathContext returns the page and the first line of every block above
+// block, as written in front of an {{embed-path}}.
+func (c *Converter) embedPathContext(block *roam.Child) string {
+	parts := []string{"[[" + block.Page.Title + "]]"}
+	for _, ancestor := range blockAncestors(block.Page, block.UID) {
+		text := c.sourceText(ancestor)
+		if i := strings.Index(text, "\n"); i >= 0 {
+			text = text[:i]
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			parts = append(parts, text)
+		}
+	}
+
+	return strings.Join(parts, " > ") + " > "
+}
+
+// blockAncestors returns the blocks of parent that the block with uid is
+// nested in, outermost first.
+func blockAncestors(parent roam.Parent, uid string) []*roam.Child {
+	children := parent.Children()
+	for i := range children {
+		child := &children[i]
+		if child.UID == uid {
+			return []*roam.Child{}
+		}
+		if path := blockAncestors(child, uid); path != nil {
+			return append([]*roam.Child{child}, path...)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package convert
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// stripTitleEmoji gives pages whose title starts with emoji a filename
+// without them. The original title is kept as an alias so the page can
+// still be found by it, and links are rewritten to the new filename.
+func (c *Converter) stripTitleEmoji(pages []roam.Page) {
+	for i, page := range pages {
+		if _, ok := c.quarantined[i]; ok || page.IsDaily {
+			continue
+		}
+
+		title := c.fileTitle(page.Title)
+		stripped := trimLeadingEmoji(title)
+		if stripped == title || stripped == "" {
+			continue
+		}
+
+		c.log.Debug("strip title emoji", "page", page.Title, "title", stripped)
+
+		c.renamed[page.Title] = stripped
+		if !containsString(c.aliases[page.Title], page.Title) {
+			c.aliases[page.Title] = append(c.aliases[page.Title], page.Title)
+		}
+	}
+}
+
+// trimLeadingEmoji removes the emoji and the spaces after them from the
+// start of s.
+func trimLeadingEmoji(s string) string {
+	rest := s
+	for rest != "" {
+		r, size := utf8.DecodeRuneInString(rest)
+		if !isEmojiRune(r) {
+			break
+		}
+		rest = rest[size:]
+	}
+
+	if rest == s {
+		return s
+	}
+
+	return strings.TrimLeftFunc(rest, unicode.IsSpace)
+}
+
+// isEmojiRune reports whether r is part of an emoji: a pictographic symbol,
+// a skin tone modifier, or one of the joiners and selectors that combine
+// them.
+func isEmojiRune(r rune) bool {
+	switch r {
+	case '\u200d', '\ufe0f', '\u20e3':
+		return true
+	}
+
+	return r > 0x7f && (unicode.Is(unicode.So, r) || unicode.Is(unicode.Sk, r))
+}
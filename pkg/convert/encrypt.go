@@ -0,0 +1,89 @@
+package convert
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"unicode/utf8"
+)
+
+const (
+	EncryptedStrip   = "strip"
+	EncryptedCallout = "callout"
+	EncryptedDecrypt = "decrypt"
+)
+
+// encryptedBlock applies the encrypted block policy to an {{encrypt}} call.
+// Blocks that can't be decrypted are written as callouts.
+func (c *Converter) encryptedBlock(call componentCall) (string, bool) {
+	switch c.opts.Encrypted {
+	case EncryptedStrip:
+		return "", true
+	case EncryptedDecrypt:
+		text, err := decryptRoam(call.Args, c.opts.EncryptionPassword)
+		if err == nil {
+			return text, true
+		}
+		if c.writing {
+			c.log.Warn("decrypt block", "page", c.page.Title, "block", call.BlockUID, "error", err.Error())
+		}
+	}
+
+	return "> [!note]- Encrypted block #encrypted\n> `" + call.Args + "`", true
+}
+
+// decryptRoam decrypts text encrypted by Roam, which uses the CryptoJS
+// passphrase format: base64 of "Salted__", an 8 byte salt and AES-256-CBC
+// ciphertext, with the key and IV derived from the passphrase with MD5.
+func decryptRoam(ciphertext, password string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < 16 || string(data[:8]) != "Salted__" {
+		return "", errors.New("not CryptoJS ciphertext")
+	}
+
+	salt, data := data[8:16], data[16:]
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return "", errors.New("ciphertext is not a whole number of blocks")
+	}
+
+	key, iv := deriveKey([]byte(password), salt, 32, aes.BlockSize)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > aes.BlockSize || !bytes.Equal(plain[len(plain)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return "", errors.New("wrong password")
+	}
+	plain = plain[:len(plain)-pad]
+	if !utf8.Valid(plain) {
+		return "", errors.New("wrong password")
+	}
+
+	return string(plain), nil
+}
+
+// deriveKey is OpenSSL's EVP_BytesToKey with MD5 and one iteration.
+func deriveKey(password, salt []byte, keyLen, ivLen int) ([]byte, []byte) {
+	var out, prev []byte
+	for len(out) < keyLen+ivLen {
+		h := md5.New()
+		h.Write(prev)
+		h.Write(password)
+		h.Write(salt)
+		prev = h.Sum(nil)
+		out = append(out, prev...)
+	}
+
+	return out[:keyLen], out[keyLen : keyLen+ivLen]
+}
@@ -0,0 +1,241 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	skipReasonExcluded    = "excluded"
+	skipReasonNotIncluded = "not included"
+	skipReasonDailyRange  = "outside daily range"
+	skipReasonNotSelected = "not selected"
+
+	// SkippedRefsLink writes refs to blocks on skipped pages like any other
+	// ref, with a link to a page that isn't in the vault.
+	SkippedRefsLink = "link"
+	// SkippedRefsText writes only the text of the referenced block.
+	SkippedRefsText = "text"
+)
+
+// CompileExclude compiles a title pattern for Options.Exclude and
+// Options.Include. The pattern has to match the whole title.
+func CompileExclude(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("exclude pattern %q: %w", pattern, err)
+	}
+
+	return re, nil
+}
+
+// excludePages skips the pages whose title matches one of patterns.
+func (c *Converter) excludePages(pages []roam.Page, patterns []*regexp.Regexp) {
+	for i, page := range pages {
+		if _, ok := c.quarantined[i]; ok || page.Title == "" {
+			continue
+		}
+
+		for _, re := range patterns {
+			if !re.MatchString(page.Title) {
+				continue
+			}
+
+			c.log.Debug("skip page", "page", page.Title, "reason", skipReasonExcluded, "pattern", re.String())
+			c.skipped[i] = skipReasonExcluded
+			c.excluded[page.Title] = struct{}{}
+			c.report.Skipped = append(c.report.Skipped, ReportEntry{Page: page.Title, Reason: skipReasonExcluded})
+			break
+		}
+	}
+}
+
+// includePages skips the pages whose title matches none of patterns. With
+// linked set, pages linked from a matching page are kept too.
+func (c *Converter) includePages(pages []roam.Page, patterns []*regexp.Regexp, linked bool) {
+	if len(patterns) == 0 {
+		return
+	}
+
+	keep := map[string]struct{}{}
+	for _, page := range pages {
+		if !matchesAny(patterns, page.Title) {
+			continue
+		}
+		keep[page.Title] = struct{}{}
+
+		if linked {
+			for _, title := range c.pageLinks(&page) {
+				keep[title] = struct{}{}
+			}
+		}
+	}
+
+	for i, page := range pages {
+		if _, ok := c.quarantined[i]; ok || page.Title == "" {
+			continue
+		}
+		if _, ok := c.skipped[i]; ok {
+			continue
+		}
+		if _, ok := keep[page.Title]; ok {
+			continue
+		}
+
+		c.log.Debug("skip page", "page", page.Title, "reason", skipReasonNotIncluded)
+		c.skipped[i] = skipReasonNotIncluded
+		c.excluded[page.Title] = struct{}{}
+		c.report.Skipped = append(c.report.Skipped, ReportEntry{Page: page.Title, Reason: skipReasonNotIncluded})
+	}
+}
+
+// selectPages skips every page but the ones titled titles. Daily notes can
+// be given by their Roam or converted title. The pages left out aren't
+// listed in the report, as they are usually most of the export.
+func (c *Converter) selectPages(pages []roam.Page, titles []string) error {
+	if len(titles) == 0 {
+		return nil
+	}
+
+	wanted := make([]string, len(titles))
+	keep := map[string]bool{}
+	for i, title := range titles {
+		if t, ok, err := c.parseDailyTitle(title); ok && err == nil {
+			title = c.formatDaily(t)
+		}
+		wanted[i] = title
+		keep[title] = false
+	}
+
+	for i, page := range pages {
+		if _, ok := keep[page.Title]; ok {
+			keep[page.Title] = true
+			continue
+		}
+		if _, ok := c.skipped[i]; ok || page.Title == "" {
+			continue
+		}
+
+		c.skipped[i] = skipReasonNotSelected
+		c.excluded[page.Title] = struct{}{}
+	}
+
+	for _, title := range wanted {
+		if !keep[title] {
+			return fmt.Errorf("page %q is not in the export", title)
+		}
+	}
+
+	return nil
+}
+
+// skipDailyRange skips the daily pages dated before from or after to. A zero
+// time leaves that end of the range open.
+func (c *Converter) skipDailyRange(pages []roam.Page, from, to time.Time) {
+	if from.IsZero() && to.IsZero() {
+		return
+	}
+
+	for i, page := range pages {
+		if _, ok := c.quarantined[i]; ok || !page.IsDaily {
+			continue
+		}
+		if _, ok := c.skipped[i]; ok {
+			continue
+		}
+
+		t := c.dailyDates[page.Title]
+		if (from.IsZero() || !t.Before(from)) && (to.IsZero() || !t.After(to)) {
+			continue
+		}
+
+		c.log.Debug("skip page", "page", page.Title, "reason", skipReasonDailyRange)
+		c.skipped[i] = skipReasonDailyRange
+		c.excluded[page.Title] = struct{}{}
+		c.report.Skipped = append(c.report.Skipped, ReportEntry{Page: page.Title, Reason: skipReasonDailyRange})
+	}
+}
+
+// pageLinks returns the titles of the pages that page links to or references
+// blocks on.
+func (c *Converter) pageLinks(page *roam.Page) []string {
+	var titles []string
+
+	var walk func(children []roam.Child)
+	walk = func(children []roam.Child) {
+		for _, child := range children {
+			for _, target := range linkTargets(child.String) {
+				if t, ok, err := c.parseDailyTitle(target); ok && err == nil {
+					target = c.formatDaily(t)
+				}
+				titles = append(titles, target)
+			}
+
+			for _, uid := range reBlockRef.FindAllStringSubmatch(child.String, -1) {
+				if ref, ok := c.uidBlock[uid[2]]; ok {
+					titles = append(titles, ref.Page.Title)
+				}
+			}
+
+			walk(child.RawChildren)
+		}
+	}
+	walk(page.RawChildren)
+
+	return titles
+}
+
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unlinkExcluded turns links and tags that point at excluded pages into
+// plain text.
+func (c *Converter) unlinkExcluded(s string) string {
+	if len(c.excluded) == 0 || !strings.ContainsAny(s, "[#") {
+		return s
+	}
+
+	s = reTagToken.ReplaceAllStringFunc(s, func(token string) string {
+		m := reTagToken.FindStringSubmatch(token)
+		title := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(m[2], "#"), "[["), "]]")
+		if _, ok := c.excluded[title]; !ok {
+			return token
+		}
+
+		return m[1] + title
+	})
+
+	return reWikiLink.ReplaceAllStringFunc(s, func(link string) string {
+		inner := link[2 : len(link)-2]
+
+		target, text := inner, inner
+		if i := strings.Index(inner, "|"); i >= 0 {
+			target, text = inner[:i], inner[i+1:]
+		}
+
+		title := target
+		if i := strings.Index(target, "#"); i >= 0 {
+			title = target[:i]
+			if text == target {
+				text = title
+			}
+		}
+
+		if _, ok := c.excluded[title]; !ok {
+			return link
+		}
+
+		return text
+	})
+}
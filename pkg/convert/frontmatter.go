@@ -0,0 +1,73 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// frontmatter returns the YAML frontmatter for page, or nothing when the page
+// has no properties to write. Logseq pages get page properties instead, and
+// HTML pages have none.
+func (c *Converter) frontmatter(page *roam.Page) []string {
+	aliases := c.aliases[page.Title]
+	tags := c.pageTags[page.Title]
+	reactions := c.pageReactions[page.Title]
+	_, slugged := c.renamed[page.Title]
+	slugged = slugged && c.opts.Slug
+	author := ""
+	if c.opts.AuthorFrontmatter {
+		author = c.pageAuthor(page)
+	}
+	if (len(aliases) == 0 && len(tags) == 0 && len(reactions) == 0 && !slugged && author == "") || c.opts.Target == TargetHTML {
+		return nil
+	}
+
+	if c.logseq() {
+		var props []string
+		if len(aliases) > 0 {
+			props = append(props, "alias:: "+strings.Join(aliases, ", "))
+		}
+		if len(tags) > 0 {
+			props = append(props, "tags:: "+strings.Join(tags, ", "))
+		}
+		if author != "" {
+			props = append(props, "author:: "+author)
+		}
+		if len(props) == 0 {
+			return nil
+		}
+		return append(props, "")
+	}
+
+	lines := []string{"---"}
+	if slugged {
+		lines = append(lines, fmt.Sprintf("title: %q", page.Title))
+	}
+	if len(aliases) > 0 {
+		lines = append(lines, "aliases:")
+		for _, alias := range aliases {
+			lines = append(lines, fmt.Sprintf("  - %q", alias))
+		}
+	}
+	if len(tags) > 0 {
+		lines = append(lines, "tags:")
+		for _, tag := range tags {
+			// YAML tags can't hold spaces
+			lines = append(lines, fmt.Sprintf("  - %q", strings.ReplaceAll(tag, " ", "-")))
+		}
+	}
+	if author != "" {
+		lines = append(lines, fmt.Sprintf("author: %q", author))
+	}
+	if len(reactions) > 0 {
+		lines = append(lines, "reactions:")
+		for _, r := range reactions {
+			lines = append(lines, fmt.Sprintf("  %q: %d", r.emoji, r.count))
+		}
+	}
+	lines = append(lines, "---")
+
+	return lines
+}
@@ -0,0 +1,169 @@
+package convert
+
+import (
+	"sort"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// GraphReport describes the link structure of an export.
+type GraphReport struct {
+	Pages  int `json:"pages"`
+	Blocks int `json:"blocks"`
+	// Orphans are the non-daily pages no other page links to.
+	Orphans []string `json:"orphans"`
+	// MostLinked are the pages with the most links from other pages.
+	MostLinked []PageCount `json:"most_linked"`
+	// Broken are links to pages that aren't in the export.
+	Broken []BrokenLink `json:"broken"`
+	// Largest are the pages with the most blocks.
+	Largest []PageCount `json:"largest"`
+	// Hub is the page link depths are measured from, and Depth the number
+	// of links needed to reach each page from it. Pages missing from Depth
+	// can't be reached. Depth is empty when the hub isn't in the export.
+	Hub   string         `json:"hub,omitempty"`
+	Depth map[string]int `json:"depth,omitempty"`
+}
+
+// PageCount is a page with a count, of links or blocks.
+type PageCount struct {
+	Page  string `json:"page"`
+	Count int    `json:"count"`
+}
+
+// BrokenLink is a link target that has no page, with the pages linking to
+// it.
+type BrokenLink struct {
+	Target string   `json:"target"`
+	From   []string `json:"from"`
+}
+
+// Analyze reports on the links between pages without converting them. The
+// lists of pages are cut to the top entries. Link depths are measured from
+// hub when it is set.
+func Analyze(pages []roam.Page, top int, hub string) *GraphReport {
+	r := &GraphReport{Pages: len(pages), Hub: hub}
+
+	titles := map[string]struct{}{}
+	uidPage := map[string]string{}
+	for i := range pages {
+		titles[pages[i].Title] = struct{}{}
+
+		var walk func(children []roam.Child)
+		walk = func(children []roam.Child) {
+			for _, child := range children {
+				uidPage[child.UID] = pages[i].Title
+				walk(child.RawChildren)
+			}
+		}
+		walk(pages[i].RawChildren)
+	}
+
+	inbound := map[string]int{}
+	outbound := map[string][]string{}
+	broken := map[string][]string{}
+	var brokenTargets []string
+	var sizes []PageCount
+
+	for i := range pages {
+		page := &pages[i]
+		blocks := 0
+		linked := map[string]struct{}{}
+
+		var walk func(children []roam.Child)
+		walk = func(children []roam.Child) {
+			for _, child := range children {
+				blocks++
+
+				targets := linkTargets(child.String)
+				for _, m := range reBlockRef.FindAllStringSubmatch(child.String, -1) {
+					if title, ok := uidPage[m[2]]; ok {
+						targets = append(targets, title)
+					}
+				}
+
+				for _, target := range targets {
+					if target == page.Title {
+						continue
+					}
+					if _, ok := linked[target]; ok {
+						continue
+					}
+					linked[target] = struct{}{}
+
+					if _, ok := titles[target]; !ok {
+						if _, ok := broken[target]; !ok {
+							brokenTargets = append(brokenTargets, target)
+						}
+						broken[target] = append(broken[target], page.Title)
+						continue
+					}
+
+					inbound[target]++
+					outbound[page.Title] = append(outbound[page.Title], target)
+				}
+
+				walk(child.RawChildren)
+			}
+		}
+		walk(page.RawChildren)
+
+		r.Blocks += blocks
+		sizes = append(sizes, PageCount{Page: page.Title, Count: blocks})
+	}
+
+	for _, page := range pages {
+		if _, ok, _ := roam.ParseDate(page.Title); ok {
+			continue
+		}
+		if inbound[page.Title] == 0 && page.Title != "" {
+			r.Orphans = append(r.Orphans, page.Title)
+		}
+	}
+	sort.Strings(r.Orphans)
+
+	for title, n := range inbound {
+		r.MostLinked = append(r.MostLinked, PageCount{Page: title, Count: n})
+	}
+	r.MostLinked = topCounts(r.MostLinked, top)
+	r.Largest = topCounts(sizes, top)
+
+	sort.Strings(brokenTargets)
+	for _, target := range brokenTargets {
+		r.Broken = append(r.Broken, BrokenLink{Target: target, From: broken[target]})
+	}
+
+	if _, ok := titles[hub]; ok && hub != "" {
+		r.Depth = map[string]int{hub: 0}
+		queue := []string{hub}
+		for len(queue) > 0 {
+			title := queue[0]
+			queue = queue[1:]
+			for _, next := range outbound[title] {
+				if _, ok := r.Depth[next]; !ok {
+					r.Depth[next] = r.Depth[title] + 1
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+
+	return r
+}
+
+// topCounts sorts counts from highest to lowest and keeps the first n, or
+// all of them when n is 0.
+func topCounts(counts []PageCount, n int) []PageCount {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Page < counts[j].Page
+	})
+
+	if n > 0 && len(counts) > n {
+		counts = counts[:n]
+	}
+
+	return counts
+}
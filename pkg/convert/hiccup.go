@@ -0,0 +1,239 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	HiccupPassthrough = "passthrough"
+	HiccupStrip       = "strip"
+)
+
+// hiccupTags are the elements that are converted. Anything else is handled by
+// the fallback policy.
+var hiccupTags = map[string]struct{}{
+	"a": {}, "b": {}, "blockquote": {}, "br": {}, "code": {}, "div": {}, "em": {},
+	"h1": {}, "h2": {}, "h3": {}, "h4": {}, "h5": {}, "h6": {}, "hr": {}, "i": {},
+	"img": {}, "li": {}, "ol": {}, "p": {}, "pre": {}, "s": {}, "small": {},
+	"span": {}, "strong": {}, "sub": {}, "sup": {}, "u": {}, "ul": {},
+}
+
+var hiccupVoidTags = map[string]struct{}{"br": {}, "hr": {}, "img": {}}
+
+// replaceHiccup converts a block that begins with :hiccup into Markdown or
+// HTML. Blocks that can't be parsed or use unsupported elements, or that
+// need HTML when allowHTML is false, are either kept as inline code or
+// removed, depending on fallback.
+func replaceHiccup(s, fallback string, allowHTML bool) string {
+	src := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), ":hiccup"))
+
+	out, err := hiccupToHTML(src)
+	if err == nil && (allowHTML || !strings.HasPrefix(out, "<")) {
+		return out
+	}
+
+	if fallback == HiccupStrip {
+		return ""
+	}
+
+	return "`" + strings.ReplaceAll(s, "`", "'") + "`"
+}
+
+func isHiccup(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), ":hiccup")
+}
+
+func hiccupToHTML(src string) (string, error) {
+	v, err := roam.ParseEDN(src)
+	if err != nil {
+		return "", err
+	}
+
+	vec, ok := v.(roam.EDNVector)
+	if !ok {
+		return "", errors.New("hiccup form is not a vector")
+	}
+
+	// a top level rule or image reads better as Markdown
+	if tag, attrs, children, err := splitHiccup(vec); err == nil && len(children) == 0 {
+		switch tag.name {
+		case "hr":
+			if len(attrs) == 0 && len(tag.classes) == 0 {
+				return "---", nil
+			}
+		case "img":
+			if src, ok := attrs["src"]; ok && len(tag.classes) == 0 && tag.id == "" && onlyKeys(attrs, "src", "alt") {
+				return fmt.Sprintf("![%s](%s)", roam.EDNString(attrs["alt"]), roam.EDNString(src)), nil
+			}
+		}
+	}
+
+	var sb strings.Builder
+	if err := renderHiccup(&sb, vec); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+func renderHiccup(sb *strings.Builder, v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		sb.WriteString(html.EscapeString(v))
+		return nil
+	case roam.EDNVector:
+		tag, attrs, children, err := splitHiccup(v)
+		if err != nil {
+			return err
+		}
+
+		sb.WriteString("<" + tag.name)
+		if tag.id != "" {
+			fmt.Fprintf(sb, " id=%q", tag.id)
+		}
+		classes := tag.classes
+		if class, ok := attrs["class"]; ok {
+			classes = append(classes, roam.EDNString(class))
+			delete(attrs, "class")
+		}
+		if len(classes) > 0 {
+			fmt.Fprintf(sb, " class=%q", html.EscapeString(strings.Join(classes, " ")))
+		}
+
+		keys := make([]string, 0, len(attrs))
+		for k := range attrs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(sb, ` %s="%s"`, k, html.EscapeString(roam.EDNString(attrs[k])))
+		}
+		sb.WriteString(">")
+
+		if _, ok := hiccupVoidTags[tag.name]; ok {
+			if len(children) > 0 {
+				return fmt.Errorf("%s can't have children", tag.name)
+			}
+			return nil
+		}
+
+		for _, child := range children {
+			if err := renderHiccup(sb, child); err != nil {
+				return err
+			}
+		}
+		sb.WriteString("</" + tag.name + ">")
+
+		return nil
+	case float64, roam.EDNKeyword:
+		sb.WriteString(html.EscapeString(roam.EDNString(v)))
+		return nil
+	default:
+		return fmt.Errorf("unsupported hiccup value %v", v)
+	}
+}
+
+type hiccupTag struct {
+	name    string
+	id      string
+	classes []string
+}
+
+func splitHiccup(v roam.EDNVector) (hiccupTag, map[string]interface{}, []interface{}, error) {
+	if len(v) == 0 {
+		return hiccupTag{}, nil, nil, errors.New("empty hiccup form")
+	}
+
+	kw, ok := v[0].(roam.EDNKeyword)
+	if !ok {
+		return hiccupTag{}, nil, nil, errors.New("hiccup form does not start with a tag")
+	}
+
+	tag := parseHiccupTag(string(kw))
+	if _, ok := hiccupTags[tag.name]; !ok {
+		return hiccupTag{}, nil, nil, fmt.Errorf("unsupported element %q", tag.name)
+	}
+
+	attrs := map[string]interface{}{}
+	rest := v[1:]
+	if len(rest) > 0 {
+		if m, ok := rest[0].(roam.EDNMap); ok {
+			for k, val := range m {
+				if k == "style" {
+					val = hiccupStyle(val)
+				}
+				attrs[k] = val
+			}
+			rest = rest[1:]
+		}
+	}
+
+	return tag, attrs, rest, nil
+}
+
+func parseHiccupTag(s string) hiccupTag {
+	var tag hiccupTag
+
+	name := s
+	if i := strings.IndexAny(name, ".#"); i >= 0 {
+		name, s = s[:i], s[i:]
+		for s != "" {
+			kind := s[0]
+			s = s[1:]
+			end := strings.IndexAny(s, ".#")
+			if end < 0 {
+				end = len(s)
+			}
+			if kind == '#' {
+				tag.id = s[:end]
+			} else {
+				tag.classes = append(tag.classes, s[:end])
+			}
+			s = s[end:]
+		}
+	}
+	tag.name = name
+
+	return tag
+}
+
+func hiccupStyle(v interface{}) interface{} {
+	m, ok := v.(roam.EDNMap)
+	if !ok {
+		return v
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s:%s", k, roam.EDNString(m[k])))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func onlyKeys(m map[string]interface{}, keys ...string) bool {
+	allowed := map[string]struct{}{}
+	for _, k := range keys {
+		allowed[k] = struct{}{}
+	}
+
+	for k := range m {
+		if _, ok := allowed[k]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,197 @@
+package convert
+
+import (
+	"bytes"
+	"html/template"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// TargetHTML writes a standalone HTML file per page and an index.html.
+const TargetHTML = "html"
+
+const htmlIndex = "index.html"
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote),
+	goldmark.WithRendererOptions(html.WithHardWraps(), html.WithUnsafe()),
+)
+
+var htmlPage = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; line-height: 1.5; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+a { color: #2a5db0; }
+a:target { background: #fff3b0; }
+pre { background: #f5f5f5; padding: 0.5rem; overflow-x: auto; }
+blockquote { border-left: 3px solid #ccc; margin-left: 0; padding-left: 1rem; color: #555; }
+nav { margin-bottom: 1rem; }
+</style>
+</head>
+<body>
+<nav><a href="{{.Index}}">Index</a></nav>
+<main>
+<h1>{{.Title}}</h1>
+{{.Body}}
+</main>
+</body>
+</html>
+`))
+
+type htmlPageData struct {
+	Title string
+	Index string
+	Body  template.HTML
+}
+
+// htmlPaths maps the file title of every page that is written to its path,
+// so links can be pointed at the HTML files.
+func (c *Converter) htmlPaths(pages []roam.Page) {
+	for i := range pages {
+		if _, ok := c.quarantined[i]; ok || pages[i].Title == "" {
+			continue
+		}
+		if _, ok := c.skipped[i]; ok {
+			continue
+		}
+		c.htmlFiles[c.fileTitle(pages[i].Title)] = c.pagePath(&pages[i]) + ".html"
+	}
+}
+
+// renderHTML renders the Markdown of a page into a standalone HTML page
+// written at dest.
+func (c *Converter) renderHTML(title, dest string, lines []string) ([]byte, error) {
+	dir := path.Dir(dest)
+	md := c.htmlLinks(strings.Join(lines, "\n"), dir)
+
+	var body bytes.Buffer
+	if err := markdown.Convert([]byte(md), &body); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	err := htmlPage.Execute(&out, htmlPageData{
+		Title: title,
+		Index: relativeHref(dir, htmlIndex),
+		Body:  template.HTML(body.String()),
+	})
+
+	return out.Bytes(), err
+}
+
+// htmlLinks turns wikilinks and tags outside of code into Markdown links to
+// the HTML files of the pages they point at. Links to pages that aren't
+// written are left as text.
+func (c *Converter) htmlLinks(s, dir string) string {
+	return outsideCode(s, func(text string) string {
+		return c.htmlLinkText(text, dir)
+	})
+}
+
+func (c *Converter) htmlLinkText(s, dir string) string {
+	s = reTagToken.ReplaceAllStringFunc(s, func(token string) string {
+		m := reTagToken.FindStringSubmatch(token)
+		tag := strings.TrimPrefix(m[2], "#")
+		title := strings.TrimSuffix(strings.TrimPrefix(tag, "[["), "]]")
+
+		to, ok := c.htmlFiles[title]
+		if !ok {
+			return token
+		}
+
+		return m[1] + "[#" + title + "](" + relativeHref(dir, to) + ")"
+	})
+
+	return reWikiLink.ReplaceAllStringFunc(s, func(link string) string {
+		inner := link[2 : len(link)-2]
+
+		target, text := inner, inner
+		if i := strings.Index(inner, "|"); i >= 0 {
+			target, text = inner[:i], inner[i+1:]
+		}
+
+		title, anchor := target, ""
+		if i := strings.Index(target, "#"); i >= 0 {
+			title, anchor = target[:i], "#"+strings.TrimPrefix(target[i+1:], "^")
+			if text == target {
+				text = title
+			}
+		}
+
+		to, ok := c.htmlFiles[title]
+		if !ok {
+			return text
+		}
+
+		return "[" + text + "](" + relativeHref(dir, to) + anchor + ")"
+	})
+}
+
+// writeHTMLIndex writes index.html, listing the pages and then the daily
+// notes, newest first.
+func (c *Converter) writeHTMLIndex(pages []roam.Page) error {
+	var titles, dailies []string
+	for i := range pages {
+		if _, ok := c.htmlFiles[c.fileTitle(pages[i].Title)]; !ok {
+			continue
+		}
+		if pages[i].IsDaily {
+			dailies = append(dailies, pages[i].Title)
+		} else {
+			titles = append(titles, pages[i].Title)
+		}
+	}
+
+	sort.Slice(titles, func(i, j int) bool {
+		return strings.ToLower(titles[i]) < strings.ToLower(titles[j])
+	})
+	sort.Slice(dailies, func(i, j int) bool {
+		return c.dailyDates[dailies[i]].After(c.dailyDates[dailies[j]])
+	})
+
+	lines := []string{"## Pages", ""}
+	for _, title := range titles {
+		lines = append(lines, "- [["+c.fileTitle(title)+"|"+title+"]]")
+	}
+	if len(dailies) > 0 {
+		lines = append(lines, "", "## Daily notes", "")
+		for _, title := range dailies {
+			lines = append(lines, "- [["+c.fileTitle(title)+"|"+title+"]]")
+		}
+	}
+
+	data, err := c.renderHTML("Index", htmlIndex, lines)
+	if err != nil {
+		return err
+	}
+
+	return c.w.WriteFile(htmlIndex, data)
+}
+
+// relativeHref returns the URL of the vault path to, relative to the folder
+// dir.
+func relativeHref(dir, to string) string {
+	var parts []string
+	if dir != "." {
+		for range strings.Split(dir, "/") {
+			parts = append(parts, "..")
+		}
+	}
+	for _, part := range strings.Split(to, "/") {
+		parts = append(parts, url.PathEscape(part))
+	}
+
+	return strings.Join(parts, "/")
+}
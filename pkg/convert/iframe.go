@@ -0,0 +1,31 @@
+package convert
+
+import (
+	"fmt"
+	"html"
+)
+
+const (
+	IframeHTML    = "html"
+	IframeSandbox = "sandbox"
+	IframeLink    = "link"
+)
+
+// iframe writes an {{iframe: url}} component as an HTML iframe, which
+// Obsidian renders, one sandboxed so the page can't run scripts, or a plain
+// link.
+func (c *Converter) iframe(call componentCall) (string, bool) {
+	src := embedURL(call)
+	if src == "" {
+		return "", false
+	}
+
+	switch c.opts.Iframes {
+	case IframeLink:
+		return "[" + src + "](" + src + ")", true
+	case IframeSandbox:
+		return fmt.Sprintf(`<iframe src="%s" sandbox></iframe>`, html.EscapeString(src)), true
+	}
+
+	return fmt.Sprintf(`<iframe src="%s"></iframe>`, html.EscapeString(src)), true
+}
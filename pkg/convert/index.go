@@ -0,0 +1,75 @@
+package convert
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// FolderIndexReadme writes README.md into every folder.
+	FolderIndexReadme = "readme"
+	// FolderIndexAbout writes _about.md into every folder.
+	FolderIndexAbout = "about"
+)
+
+// writtenNote is a note written to the vault.
+type writtenNote struct {
+	path string
+	date time.Time
+}
+
+// addNote records a written note for the folder indexes.
+func (c *Converter) addNote(p string, date time.Time) {
+	c.notes = append(c.notes, writtenNote{path: p, date: date})
+}
+
+// writeFolderIndexes writes an index note into every folder of the vault
+// listing the notes inside it. The links are plain Markdown links so the
+// index also works in file browsers and on GitHub.
+func (c *Converter) writeFolderIndexes() error {
+	name := "README.md"
+	if c.opts.FolderIndex == FolderIndexAbout {
+		name = "_about.md"
+	}
+
+	byDir := map[string][]writtenNote{}
+	for _, note := range c.notes {
+		if dir := path.Dir(note.path); dir != "." {
+			byDir[dir] = append(byDir[dir], note)
+		}
+	}
+
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		notes := byDir[dir]
+		sort.Slice(notes, func(i, j int) bool {
+			return notes[i].path < notes[j].path
+		})
+
+		lines := []string{"# " + path.Base(dir), ""}
+		for _, note := range notes {
+			file := path.Base(note.path)
+			name := strings.TrimSuffix(file, ".md")
+			line := fmt.Sprintf("- [%s](%s)", name, url.PathEscape(file))
+			if date := note.date.Format(obsDailyLayout); !note.date.IsZero() && date != name {
+				line += " (" + date + ")"
+			}
+			lines = append(lines, line)
+		}
+
+		if err := c.w.WriteFile(dir+"/"+name, []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+			return fmt.Errorf("write index of %s: %w", dir, err)
+		}
+	}
+
+	return nil
+}
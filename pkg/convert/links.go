@@ -0,0 +1,81 @@
+package convert
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// linkTargets returns the titles of the pages s links to through [[links]],
+// #tags, #[[tags]] and attribute:: names. Nested links such as
+// [[a [[b]]]] yield both the outer and the inner title.
+func linkTargets(s string) []string {
+	var targets []string
+	var open []int
+
+	for i := 0; i < len(s)-1; i++ {
+		switch s[i : i+2] {
+		case "[[":
+			open = append(open, i+2)
+			i++
+		case "]]":
+			if len(open) == 0 {
+				continue
+			}
+			start := open[len(open)-1]
+			open = open[:len(open)-1]
+			targets = append(targets, s[start:i])
+			i++
+		}
+	}
+
+	for _, m := range reTag.FindAllStringSubmatch(s, -1) {
+		targets = append(targets, m[1])
+	}
+
+	if m := reAttribute.FindStringSubmatch(s); m != nil {
+		targets = append(targets, strings.TrimSpace(m[1]))
+	}
+
+	return targets
+}
+
+// linkedTitles counts the links to each page title from other pages. Links
+// to daily notes are counted against the converted daily title.
+func (c *Converter) linkedTitles(pages []roam.Page) map[string]int {
+	counts := map[string]int{}
+
+	var walk func(page *roam.Page, children []roam.Child)
+	walk = func(page *roam.Page, children []roam.Child) {
+		for _, child := range children {
+			for _, target := range linkTargets(child.String) {
+				if t, ok, err := c.parseDailyTitle(target); ok && err == nil {
+					target = c.formatDaily(t)
+				}
+				if target != page.Title {
+					counts[target]++
+				}
+			}
+
+			for _, uid := range reBlockRef.FindAllStringSubmatch(child.String, -1) {
+				if ref, ok := c.uidBlock[uid[2]]; ok && ref.Page.Title != page.Title {
+					counts[ref.Page.Title]++
+				}
+			}
+
+			walk(page, child.RawChildren)
+		}
+	}
+
+	for i := range pages {
+		walk(&pages[i], pages[i].RawChildren)
+	}
+
+	return counts
+}
+
+var (
+	reTag       = regexp.MustCompile(`(?:^|\s)#([\w/-]+)`)
+	reAttribute = regexp.MustCompile(`^([^:\n]+)::`)
+)
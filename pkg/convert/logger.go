@@ -0,0 +1,30 @@
+package convert
+
+// Logger receives log messages. Each message is followed by key value pairs.
+type Logger interface {
+	Warn(msg string, kv ...interface{})
+	Info(msg string, kv ...interface{})
+	Debug(msg string, kv ...interface{})
+	Trace(msg string, kv ...interface{})
+}
+
+// Progress reports how far a conversion pass has got.
+type Progress interface {
+	// Start begins a pass over total items.
+	Start(phase string, total int)
+	Increment()
+	Finish()
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Trace(string, ...interface{}) {}
+
+type nopProgress struct{}
+
+func (nopProgress) Start(string, int) {}
+func (nopProgress) Increment()        {}
+func (nopProgress) Finish()           {}
@@ -0,0 +1,67 @@
+package convert
+
+import (
+	"crypto/md5"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	// TargetObsidian writes an Obsidian vault.
+	TargetObsidian = "obsidian"
+	// TargetLogseq writes a Logseq graph: every block is a bullet, block
+	// references use block ids, pages go in pages/ and daily notes in
+	// journals/.
+	TargetLogseq = "logseq"
+
+	logseqJournalLayout = "2006_01_02"
+)
+
+func (c *Converter) logseq() bool {
+	return c.opts.Target == TargetLogseq
+}
+
+// logseqDailyTitle formats t with Logseq's default journal title format.
+func logseqDailyTitle(t time.Time) string {
+	return fmt.Sprintf("%s %d%s, %d", t.Format("Jan"), t.Day(), roam.OrdinalSuffix(t.Day()), t.Year())
+}
+
+// logseqPagePath returns the path of a page in a Logseq graph without the
+// file extension. Namespace separators are written as ___ as Logseq does.
+func (c *Converter) logseqPagePath(page *roam.Page) string {
+	if t, ok := c.dailyDates[page.Title]; ok && page.IsDaily {
+		return c.dailyFolder() + t.Format(logseqJournalLayout)
+	}
+
+	return "pages/" + strings.ReplaceAll(c.fileTitle(page.Title), "/", "___")
+}
+
+// logseqBlockID derives a stable Logseq block id from a Roam uid.
+func logseqBlockID(uid string) string {
+	sum := md5.Sum([]byte("roam:" + uid))
+	sum[6] = sum[6]&0x0f | 0x30
+	sum[8] = sum[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// logseqBlockRef returns the Logseq form of a block reference or embed.
+func logseqBlockRef(uid string, embed bool) string {
+	if embed {
+		return "{{embed ((" + logseqBlockID(uid) + "))}}"
+	}
+
+	return "((" + logseqBlockID(uid) + "))"
+}
+
+// logseqLink returns a link to target shown as text.
+func logseqLink(target, text string) string {
+	if text == "" || text == target {
+		return "[[" + target + "]]"
+	}
+
+	return "[" + text + "]([[" + target + "]])"
+}
@@ -0,0 +1,59 @@
+package convert
+
+import (
+	"regexp"
+	"strings"
+)
+
+// replaceMath converts Roam math delimiters to Obsidian's. Roam writes all
+// math as $$...$$; Obsidian treats $$ as display math and $ as inline math.
+// A block that consists of nothing but a math expression is kept as display
+// math, any other $$...$$ becomes inline math. Single dollar signs (currency
+// amounts) are never turned into math. When inline math is emitted they are
+// escaped so Obsidian can't pair them with a math delimiter.
+func replaceMath(s string) string {
+	if !strings.Contains(s, "$$") {
+		return s
+	}
+
+	trimmed := strings.TrimSpace(s)
+	if m := reMathDisplay.FindStringSubmatch(trimmed); m != nil {
+		return "$$" + strings.TrimSpace(m[1]) + "$$"
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, match := range reMath.FindAllStringSubmatchIndex(s, -1) {
+		sb.WriteString(escapeDollars(s[last:match[0]]))
+		sb.WriteString("$")
+		sb.WriteString(strings.TrimSpace(s[match[2]:match[3]]))
+		sb.WriteString("$")
+		last = match[1]
+	}
+	sb.WriteString(escapeDollars(s[last:]))
+
+	return sb.String()
+}
+
+// escapeDollars escapes dollar signs that are not already escaped. Dollar
+// signs in wikilinks, link targets and URLs are part of a name or address,
+// so they are left alone.
+func escapeDollars(s string) string {
+	spans := reDollarLiteral.FindAllStringIndex(s, -1)
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '$' && (i == 0 || s[i-1] != '\\') && !inCode(spans, i, i+1) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(s[i])
+	}
+
+	return sb.String()
+}
+
+var (
+	reMath          = regexp.MustCompile(`(?s)\$\$(.+?)\$\$`)
+	reMathDisplay   = regexp.MustCompile(`(?s)^\$\$((?:[^$]|\$[^$])+)\$\$$`)
+	reDollarLiteral = regexp.MustCompile(`\[\[[^\[\]\n]*\]\]|\]\([^)\s]*\)|\b[a-zA-Z][\w+.-]*://\S+`)
+)
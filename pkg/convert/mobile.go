@@ -0,0 +1,81 @@
+package convert
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	// mobileMaxName is the longest file or folder name, in bytes, written
+	// in mobile safe mode.
+	mobileMaxName = 80
+	// mobileMaxFolders is the deepest folder nesting of a namespaced page
+	// in mobile safe mode.
+	mobileMaxFolders = 2
+)
+
+// mobileTitles gives pages whose filename is too long or too deeply nested
+// for Obsidian Mobile a shorter one. Folders below mobileMaxFolders are
+// folded into the filename and long names are cut. The original title is kept
+// as an alias.
+func (c *Converter) mobileTitles(pages []roam.Page) {
+	for i, page := range pages {
+		if _, ok := c.quarantined[i]; ok || page.IsDaily || page.Title == "" {
+			continue
+		}
+
+		title := c.fileTitle(page.Title)
+		short := mobileTitle(title)
+		if short == title {
+			continue
+		}
+
+		c.log.Debug("shorten title for mobile", "page", page.Title, "title", short)
+
+		c.renamed[page.Title] = short
+		if !containsString(c.aliases[page.Title], page.Title) {
+			c.aliases[page.Title] = append(c.aliases[page.Title], page.Title)
+		}
+	}
+}
+
+// mobileTitle returns title with at most mobileMaxFolders folders and every
+// name cut to mobileMaxName bytes.
+func mobileTitle(title string) string {
+	parts := strings.Split(title, "/")
+	if len(parts) > mobileMaxFolders+1 {
+		parts = append(parts[:mobileMaxFolders], strings.Join(parts[mobileMaxFolders:], " - "))
+	}
+
+	for i, part := range parts {
+		parts[i] = truncateName(part, mobileMaxName)
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// truncateName cuts s to at most max bytes without splitting a rune.
+func truncateName(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+
+	s = s[:max]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+
+	return strings.TrimSpace(s)
+}
+
+// embedLink replaces Roam media embeds with a plain link in mobile safe mode,
+// since iframes are slow and often broken in Obsidian Mobile.
+func (c *Converter) embedLink(call componentCall) (string, bool) {
+	if !c.opts.MobileSafe || call.Args == "" {
+		return "", false
+	}
+
+	return "[" + call.Args + "](" + call.Args + ")", true
+}
@@ -0,0 +1,135 @@
+package convert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	// MOCLetter groups the pages in the index note by first letter.
+	MOCLetter = "letter"
+	// MOCNamespace groups the pages in the index note by top-level
+	// namespace.
+	MOCNamespace = "namespace"
+
+	mocTitle = "Index"
+)
+
+// writeMOCs writes an index note listing every converted page, grouped per
+// the MOC option, and a hub note for every namespace that has no page of its
+// own. Daily notes aren't listed.
+func (c *Converter) writeMOCs(pages []roam.Page) error {
+	existing := map[string]struct{}{}
+	var titles []string
+	for i, page := range pages {
+		existing[page.Title] = struct{}{}
+
+		if _, ok := c.quarantined[i]; ok || page.Title == "" || page.IsDaily {
+			continue
+		}
+		if _, ok := c.skipped[i]; ok {
+			continue
+		}
+		titles = append(titles, page.Title)
+	}
+	sort.Slice(titles, func(i, j int) bool {
+		return strings.ToLower(titles[i]) < strings.ToLower(titles[j])
+	})
+
+	if _, ok := existing[mocTitle]; ok {
+		c.log.Warn("a page is already named Index, the index note is not written")
+	} else if err := c.writeIndexNote(titles); err != nil {
+		return err
+	}
+
+	return c.writeHubNotes(titles, existing)
+}
+
+func (c *Converter) writeIndexNote(titles []string) error {
+	var groups []string
+	byGroup := map[string][]string{}
+	for _, title := range titles {
+		group := mocGroup(title, c.opts.MOC)
+		if _, ok := byGroup[group]; !ok {
+			groups = append(groups, group)
+		}
+		byGroup[group] = append(byGroup[group], title)
+	}
+	sort.Strings(groups)
+
+	var lines []string
+	for _, group := range groups {
+		lines = append(lines, "## "+group, "")
+		for _, title := range byGroup[group] {
+			lines = append(lines, "- "+c.rewriteLinks("[["+title+"]]"))
+		}
+		lines = append(lines, "")
+	}
+
+	return c.w.WriteFile(mocTitle+".md", []byte(strings.Join(lines, "\n")))
+}
+
+// mocGroup returns the index section a title is listed in.
+func mocGroup(title, mode string) string {
+	if mode == MOCNamespace {
+		if i := strings.Index(title, "/"); i > 0 {
+			return title[:i]
+		}
+		return "Pages"
+	}
+
+	r, _ := utf8.DecodeRuneInString(title)
+	if !unicode.IsLetter(r) {
+		return "#"
+	}
+
+	return string(unicode.ToUpper(r))
+}
+
+// writeHubNotes writes a note for every namespace that lists the pages and
+// namespaces directly inside it. Namespaces that are pages already are left
+// alone.
+func (c *Converter) writeHubNotes(titles []string, existing map[string]struct{}) error {
+	children := map[string][]string{}
+	for _, title := range titles {
+		parts := strings.Split(title, "/")
+		for i := 1; i < len(parts); i++ {
+			ns := strings.Join(parts[:i], "/")
+			child := strings.Join(parts[:i+1], "/")
+			if !containsString(children[ns], child) {
+				children[ns] = append(children[ns], child)
+			}
+		}
+	}
+
+	namespaces := make([]string, 0, len(children))
+	for ns := range children {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	for _, ns := range namespaces {
+		if _, ok := existing[ns]; ok {
+			continue
+		}
+
+		lines := []string{fmt.Sprintf("Pages in the %s namespace.", ns), ""}
+		for _, child := range children[ns] {
+			lines = append(lines, "- "+c.rewriteLinks("[["+child+"]]"))
+		}
+
+		dest := c.fileTitle(ns) + ".md"
+		if err := c.w.WriteFile(dest, []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+			return fmt.Errorf("write hub note %q: %w", ns, err)
+		}
+		c.addNote(dest, time.Time{})
+	}
+
+	return nil
+}
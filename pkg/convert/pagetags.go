@@ -0,0 +1,57 @@
+package convert
+
+import (
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	// PageTagsCopy adds the tags to the frontmatter and keeps the block.
+	PageTagsCopy = "copy"
+	// PageTagsMove adds the tags to the frontmatter and removes the block.
+	PageTagsMove = "move"
+)
+
+// collectPageTags adds the tags of a page whose first block is only a row of
+// tags to the page's frontmatter tags. With PageTagsMove a block without
+// children is removed.
+func (c *Converter) collectPageTags(page *roam.Page, mode string) {
+	if len(page.RawChildren) == 0 {
+		return
+	}
+
+	first := page.RawChildren[0]
+	tags := rowTags(first.String)
+	if len(tags) == 0 {
+		return
+	}
+
+	for _, tag := range tags {
+		if !containsString(c.pageTags[page.Title], tag) {
+			c.pageTags[page.Title] = append(c.pageTags[page.Title], tag)
+		}
+	}
+
+	if mode == PageTagsMove && len(first.Children()) == 0 {
+		page.RawChildren = page.RawChildren[1:]
+	}
+}
+
+// rowTags returns the names of the tags in s, if s holds nothing else.
+func rowTags(s string) []string {
+	var tags []string
+	rest := reTagToken.ReplaceAllStringFunc(s, func(token string) string {
+		tag := strings.TrimPrefix(reTagToken.FindStringSubmatch(token)[2], "#")
+		if strings.HasPrefix(tag, "[[") {
+			tag = tag[2 : len(tag)-2]
+		}
+		tags = append(tags, strings.Join(strings.Fields(tag), " "))
+		return ""
+	})
+	if strings.TrimSpace(rest) != "" {
+		return nil
+	}
+
+	return tags
+}
@@ -0,0 +1,122 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// imageSizes returns the widths and heights of the images in a block, by
+// URL, from the block's :image-size prop.
+func imageSizes(block *roam.Child) map[string][2]int {
+	raw := propValue(block.Props, "image-size")
+	if raw == nil {
+		return nil
+	}
+
+	var byURL map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &byURL); err != nil {
+		return nil
+	}
+
+	sizes := map[string][2]int{}
+	for url, size := range byURL {
+		var width, height float64
+		if err := json.Unmarshal(propValue(size, "width"), &width); err != nil || width <= 0 {
+			continue
+		}
+		_ = json.Unmarshal(propValue(size, "height"), &height)
+		sizes[url] = [2]int{int(width + 0.5), int(height + 0.5)}
+	}
+
+	return sizes
+}
+
+// sizeImages adds the size a Roam user gave an image, in its {:width} or
+// with the image's handle, to its Markdown: the ![alt|width](url) form
+// Obsidian reads, or ![[file|width]] for a file in the vault, or the {:width}
+// Logseq reads. HTML keeps plain images.
+func (c *Converter) sizeImages(s string, block *roam.Child) string {
+	if c.opts.Target == TargetHTML || !strings.Contains(s, "![") {
+		return s
+	}
+
+	sizes := imageSizes(block)
+	if len(sizes) == 0 && !strings.Contains(s, "{:") {
+		return s
+	}
+
+	return reImage.ReplaceAllStringFunc(s, func(image string) string {
+		m := reImage.FindStringSubmatch(image)
+		alt, src, attrs := m[1], m[2], m[3]
+
+		size, ok := sizes[src]
+		if attrs != "" {
+			size, ok = inlineImageSize(attrs)
+		}
+		if !ok || strings.Contains(alt, "|") {
+			return image
+		}
+
+		if c.logseq() {
+			if attrs != "" {
+				return image
+			}
+			return fmt.Sprintf("%s{:width %d}", image, size[0])
+		}
+
+		dim := strconv.Itoa(size[0])
+		if attrs != "" && size[1] > 0 {
+			dim += "x" + strconv.Itoa(size[1])
+		}
+
+		if !strings.Contains(src, "://") {
+			if file, err := url.PathUnescape(src); err == nil {
+				return fmt.Sprintf("![[%s|%s]]", file, dim)
+			}
+		}
+
+		return fmt.Sprintf("![%s|%s](%s)", alt, dim, src)
+	})
+}
+
+// inlineImageSize reads the width, and height if given, from the {:width
+// 300 :height 200} Roam writes after an image.
+func inlineImageSize(attrs string) ([2]int, bool) {
+	var size [2]int
+	for _, m := range reImageAttr.FindAllStringSubmatch(attrs, -1) {
+		n, err := strconv.ParseFloat(m[2], 64)
+		if err != nil || n <= 0 {
+			continue
+		}
+		if m[1] == "width" {
+			size[0] = int(n + 0.5)
+		} else {
+			size[1] = int(n + 0.5)
+		}
+	}
+
+	return size, size[0] > 0
+}
+
+// linkPreview writes a {{link-preview}} component as its bare URL, which
+// Obsidian links by itself.
+func (c *Converter) linkPreview(call componentCall) (string, bool) {
+	url := embedURL(call)
+	if url == "" {
+		return "", true
+	}
+
+	return url, true
+}
+
+var (
+	reImage       = regexp.MustCompile(`!\[([^\]\n]*)\]\(([^)\s]+)\)(\{:[^{}\n]*\})?`)
+	reImageAttr   = regexp.MustCompile(`:(width|height)\s+([\d.]+)`)
+	reMarkdownURL = regexp.MustCompile(`^\[[^\]]*\]\(([^)\s]+)\)$`)
+)
@@ -0,0 +1,100 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const quarantineDir = "_quarantine"
+
+// Error policies.
+const (
+	OnErrorFail = "fail"
+	OnErrorWarn = "warn"
+	OnErrorSkip = "skip"
+)
+
+// safely runs fn, which converts page i. Unless the error policy is
+// OnErrorFail, a failure, including a panic, sets the page aside instead of
+// aborting the run.
+func (c *Converter) safely(i int, fn func() error) (err error) {
+	if c.opts.OnError == OnErrorFail {
+		return fn()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+
+		if err != nil {
+			msg := "quarantined page"
+			if c.opts.OnError == OnErrorSkip {
+				msg = "skipped page that failed to convert"
+			}
+			c.log.Warn(msg, "index", i, "error", err.Error())
+			c.quarantined[i] = err
+			err = nil
+		}
+	}()
+
+	return fn()
+}
+
+// writeQuarantine writes every quarantined page to the quarantine folder as
+// pretty-printed Roam JSON, and adds it to the report. Pages that failed
+// under OnErrorSkip are only reported.
+func (c *Converter) writeQuarantine(pages []roam.Page) error {
+	for i, page := range pages {
+		reason, ok := c.quarantined[i]
+		if !ok {
+			continue
+		}
+
+		if c.opts.OnError == OnErrorSkip {
+			c.report.Skipped = append(c.report.Skipped, ReportEntry{
+				Page:   page.Title,
+				Reason: "failed: " + reason.Error(),
+			})
+			continue
+		}
+
+		title := page.Title
+		if title == "" {
+			title = fmt.Sprintf("untitled-%d", i)
+		}
+
+		raw, err := json.MarshalIndent(page, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		rel := quarantineDir + "/" + title + ".md"
+
+		lines := []string{
+			"> [!warning] Quarantined page",
+			fmt.Sprintf("> This page could not be converted: %s", strings.ReplaceAll(reason.Error(), "\n", " ")),
+			"> The original Roam JSON is kept below so it can be migrated by hand.",
+			"",
+			"```json",
+			string(raw),
+			"```",
+			"",
+		}
+
+		if err := c.w.WriteFile(rel, []byte(strings.Join(lines, "\n"))); err != nil {
+			return err
+		}
+
+		c.report.Quarantined = append(c.report.Quarantined, ReportEntry{
+			Page:   page.Title,
+			Path:   rel,
+			Reason: reason.Error(),
+		})
+	}
+
+	return nil
+}
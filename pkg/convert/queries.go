@@ -0,0 +1,57 @@
+package convert
+
+import (
+	"strings"
+)
+
+const (
+	QueryCallout = "callout"
+	QueryKeep    = "keep"
+	QueryStrip   = "strip"
+)
+
+// QueryBlock is a block holding a :q datalog query, which has to be
+// migrated by hand.
+type QueryBlock struct {
+	Page     string `json:"page"`
+	BlockUID string `json:"block_uid"`
+	Query    string `json:"query"`
+	// Stripped is set when the query was left out of the note.
+	Stripped bool `json:"stripped,omitempty"`
+}
+
+// isQuery reports whether s is a :q datalog query block.
+func isQuery(s string) bool {
+	s = strings.TrimSpace(s)
+	return strings.HasPrefix(s, ":q ") || strings.HasPrefix(s, ":q\n")
+}
+
+// queryBlock applies the query policy to the query in the block with uid
+// blockUID, and records it for the report. It returns false if the block is
+// stripped.
+func (c *Converter) queryBlock(s, blockUID string) (string, bool) {
+	strip := c.opts.Queries == QueryStrip
+	if c.writing {
+		c.report.Queries = append(c.report.Queries, QueryBlock{
+			Page:     c.page.Title,
+			BlockUID: blockUID,
+			Query:    strings.TrimSpace(s),
+			Stripped: strip,
+		})
+	}
+	if strip {
+		return "", false
+	}
+	c.referencedUID[blockUID] = struct{}{}
+	if c.opts.Queries == QueryKeep {
+		return s, true
+	}
+
+	lines := []string{"> [!example]- Roam query (manual migration needed)", "> ```clojure"}
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		lines = append(lines, "> "+line)
+	}
+	lines = append(lines, "> ```")
+
+	return strings.Join(lines, "\n"), true
+}
@@ -0,0 +1,54 @@
+package convert
+
+import (
+	"strings"
+)
+
+const (
+	QuotesPlain   = "plain"
+	QuotesCallout = "callout"
+
+	// quoteMarker starts a quote in the community [[>]] convention.
+	quoteMarker = "[[>]]"
+)
+
+// quoteText returns the text of a block written as a quote, with "> " or
+// [[>]] in front, without the marker.
+func quoteText(s string) (string, bool) {
+	switch {
+	case strings.HasPrefix(s, quoteMarker):
+		return strings.TrimLeft(s[len(quoteMarker):], " "), true
+	case strings.HasPrefix(s, "> "):
+		return s[2:], true
+	}
+
+	return s, false
+}
+
+// quoteLines quotes every line of s.
+func quoteLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = ">"
+			continue
+		}
+		lines[i] = "> " + line
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// quoteCallout writes the text of a quote block and its expanded children
+// as a quote callout.
+func quoteCallout(text string, children []string) string {
+	lines := []string{"[!quote]", text}
+	for _, child := range children {
+		lines = append(lines, strings.TrimSuffix(child, "\n"))
+	}
+	for len(lines) > 2 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return quoteLines(strings.Join(lines, "\n"))
+}
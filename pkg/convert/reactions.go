@@ -0,0 +1,75 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	// ReactionsDrop leaves emoji reactions out.
+	ReactionsDrop = "drop"
+	// ReactionsComment appends a block's reactions to it as a comment.
+	ReactionsComment = "comment"
+	// ReactionsFrontmatter counts the reactions of a page in its
+	// frontmatter.
+	ReactionsFrontmatter = "frontmatter"
+)
+
+// reactionCount is the number of users who reacted with an emoji.
+type reactionCount struct {
+	emoji string
+	count int
+}
+
+// blockReactions returns the reactions to a block, one per emoji.
+func blockReactions(child *roam.Child) []reactionCount {
+	var counts []reactionCount
+	for _, e := range child.Emojis {
+		native := e.Native()
+		if native == "" || len(e.Users) == 0 {
+			continue
+		}
+		counts = addReaction(counts, native, len(e.Users))
+	}
+
+	return counts
+}
+
+func addReaction(counts []reactionCount, emoji string, n int) []reactionCount {
+	for i := range counts {
+		if counts[i].emoji == emoji {
+			counts[i].count += n
+			return counts
+		}
+	}
+
+	return append(counts, reactionCount{emoji: emoji, count: n})
+}
+
+// reactions applies the reaction policy to a block. It returns the comment to
+// append to the block, if any.
+func (c *Converter) reactions(child *roam.Child) string {
+	counts := blockReactions(child)
+	if len(counts) == 0 {
+		return ""
+	}
+
+	switch c.opts.Reactions {
+	case ReactionsComment:
+		parts := make([]string, 0, len(counts))
+		for _, r := range counts {
+			parts = append(parts, fmt.Sprintf("%s×%d", r.emoji, r.count))
+		}
+		return " %% " + strings.Join(parts, " ") + " %%"
+	case ReactionsFrontmatter:
+		if c.writing {
+			for _, r := range counts {
+				c.pageReactions[c.page.Title] = addReaction(c.pageReactions[c.page.Title], r.emoji, r.count)
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,95 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	RenderStrip   = "strip"
+	RenderCallout = "callout"
+	RenderReport  = "report"
+
+	manualMigrationTitle = "Needs Manual Migration"
+)
+
+// renderUse is a roam/render component found in a block.
+type renderUse struct {
+	page         string
+	blockUID     string
+	componentUID string
+}
+
+// renderComponent applies the roam/render policy to a component call.
+func (c *Converter) renderComponent(call componentCall) (string, bool) {
+	m := reRenderArgs.FindStringSubmatch(call.Args)
+	if m == nil {
+		return "", false
+	}
+	componentUID := m[1]
+
+	switch c.opts.RenderPolicy {
+	case RenderStrip:
+		return "", true
+	case RenderReport:
+		c.renderUses[call.BlockUID] = renderUse{
+			page:         c.page.Title,
+			blockUID:     call.BlockUID,
+			componentUID: componentUID,
+		}
+		c.referencedUID[call.BlockUID] = struct{}{}
+		if _, ok := c.uidBlock[componentUID]; ok {
+			c.referencedUID[componentUID] = struct{}{}
+		}
+		return fmt.Sprintf("(roam/render component, see [[%s]])", manualMigrationTitle), true
+	default:
+		return "> [!warning] roam/render component\n> " + c.componentSource(componentUID), true
+	}
+}
+
+// componentSource describes where the code for a component lives.
+func (c *Converter) componentSource(componentUID string) string {
+	child, ok := c.uidBlock[componentUID]
+	if !ok {
+		return fmt.Sprintf("The component code (block `%s`) was not in the export.", componentUID)
+	}
+
+	c.referencedUID[componentUID] = struct{}{}
+
+	return fmt.Sprintf("Its code is in [[%s#^%s]].", child.Page.Title, componentUID)
+}
+
+// writeManualMigrationPage lists every roam/render component that was
+// collected under the report policy.
+func (c *Converter) writeManualMigrationPage() error {
+	if len(c.renderUses) == 0 {
+		return nil
+	}
+
+	uses := make([]renderUse, 0, len(c.renderUses))
+	for _, use := range c.renderUses {
+		uses = append(uses, use)
+	}
+	sort.Slice(uses, func(i, j int) bool {
+		if uses[i].page != uses[j].page {
+			return uses[i].page < uses[j].page
+		}
+		return uses[i].blockUID < uses[j].blockUID
+	})
+
+	lines := []string{
+		"These blocks used roam/render components, which can't be converted automatically.",
+		"",
+	}
+	for _, use := range uses {
+		lines = append(lines, fmt.Sprintf("- [[%s#^%s]]: %s", use.page, use.blockUID, c.componentSource(use.componentUID)))
+	}
+
+	data := strings.Join(lines, "\n") + "\n"
+
+	return c.w.WriteFile(manualMigrationTitle+".md", []byte(data))
+}
+
+var reRenderArgs = regexp.MustCompile(`^\(\((.{9})\)\)$`)
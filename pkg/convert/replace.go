@@ -0,0 +1,166 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// Replacement stages.
+const (
+	// StageSource rules change the Roam text of a block before it is
+	// converted.
+	StageSource = "source"
+	// StageMarkdown rules change the Markdown a block was converted to.
+	StageMarkdown = "markdown"
+)
+
+// Replacement is a find and replace rule applied to the text of blocks.
+type Replacement struct {
+	// Find is a regular expression.
+	Find string `json:"find"`
+	// Replace is the replacement text. $1 or ${name} insert capture groups.
+	Replace string `json:"replace"`
+	// InCode also applies the rule inside inline code and code blocks, which
+	// are left alone by default.
+	InCode bool `json:"in_code,omitempty"`
+	// Pages limits the rule to pages whose title matches this regular
+	// expression.
+	Pages string `json:"pages,omitempty"`
+	// Stage is StageSource (default) or StageMarkdown.
+	Stage string `json:"stage,omitempty"`
+
+	re    *regexp.Regexp
+	pages *regexp.Regexp
+}
+
+// LoadRules reads a JSON array of replacement rules. The rules are compiled
+// and kept in file order.
+func LoadRules(rulesPath string) ([]*Replacement, error) {
+	data, err := os.ReadFile(rulesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []*Replacement
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+
+	for i, r := range rules {
+		if err := r.Compile(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+
+	return rules, nil
+}
+
+// NewReplacement compiles a rule that replaces find with replace outside of
+// code.
+func NewReplacement(find, replace string) (*Replacement, error) {
+	r := &Replacement{Find: find, Replace: replace}
+	if err := r.Compile(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Compile compiles the rule's regular expressions and checks its stage.
+func (r *Replacement) Compile() error {
+	re, err := regexp.Compile(r.Find)
+	if err != nil {
+		return fmt.Errorf("replacement %q: %w", r.Find, err)
+	}
+	r.re = re
+
+	if r.Pages != "" {
+		pages, err := regexp.Compile(r.Pages)
+		if err != nil {
+			return fmt.Errorf("replacement %q pages: %w", r.Find, err)
+		}
+		r.pages = pages
+	}
+
+	switch r.Stage {
+	case "", StageSource, StageMarkdown:
+	default:
+		return fmt.Errorf("replacement %q has unknown stage %q", r.Find, r.Stage)
+	}
+
+	return nil
+}
+
+func (r *Replacement) applies(stage, title string) bool {
+	if r.Stage == "" {
+		if stage != StageSource {
+			return false
+		}
+	} else if r.Stage != stage {
+		return false
+	}
+
+	return r.pages == nil || r.pages.MatchString(title)
+}
+
+// Apply replaces every match in s.
+func (r *Replacement) Apply(s string) string {
+	if r.InCode {
+		return r.re.ReplaceAllString(s, r.Replace)
+	}
+
+	return outsideCode(s, func(text string) string {
+		return r.re.ReplaceAllString(text, r.Replace)
+	})
+}
+
+// applyReplacements runs the replacement rules over the blocks of page, in
+// place, so block references see the replaced text too.
+func (c *Converter) applyReplacements(page *roam.Page, children []roam.Child) {
+	for i := range children {
+		child := &children[i]
+
+		s := c.replace(StageSource, page.Title, child.String)
+		if s != child.String {
+			c.replaced[StageSource]++
+			if c.opts.DryRun {
+				c.log.Info("replace", "page", page.Title, "uid", child.UID, "from", child.String, "to", s)
+			} else {
+				c.log.Debug("replace", "page", page.Title, "uid", child.UID, "from", child.String, "to", s)
+			}
+			child.String = s
+		}
+
+		c.applyReplacements(page, child.RawChildren)
+	}
+}
+
+// replaceMarkdown runs the markdown stage rules over the converted text of a
+// block.
+func (c *Converter) replaceMarkdown(uid, s string) string {
+	updated := c.replace(StageMarkdown, c.page.Title, s)
+	if updated != s && c.writing {
+		c.replaced[StageMarkdown]++
+		if c.opts.DryRun {
+			c.log.Info("replace", "page", c.page.Title, "uid", uid, "from", s, "to", updated)
+		} else {
+			c.log.Debug("replace", "page", c.page.Title, "uid", uid, "from", s, "to", updated)
+		}
+	}
+
+	return updated
+}
+
+func (c *Converter) replace(stage, title, s string) string {
+	for _, r := range c.opts.Replacements {
+		if r.applies(stage, title) {
+			s = r.Apply(s)
+		}
+	}
+
+	return s
+}
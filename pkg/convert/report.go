@@ -0,0 +1,254 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/vault"
+)
+
+const (
+	reportTitle = "Conversion Report"
+	reportJSON  = "conversion-report.json"
+)
+
+// Report collects the problems found during a run so they can be reviewed
+// once the conversion is done.
+type Report struct {
+	Quarantined []ReportEntry   `json:"quarantined,omitempty"`
+	Collisions  []Collision     `json:"collisions,omitempty"`
+	Skipped     []ReportEntry   `json:"skipped,omitempty"`
+	Unresolved  []UnresolvedRef `json:"unresolved,omitempty"`
+	RefChains   []RefChain      `json:"ref_chains,omitempty"`
+	Queries     []QueryBlock    `json:"queries,omitempty"`
+	SmartBlocks []SmartBlock    `json:"smart_blocks,omitempty"`
+}
+
+// ReportEntry is a page that needs attention.
+type ReportEntry struct {
+	Page   string `json:"page"`
+	Path   string `json:"path,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// UnresolvedRef is a block reference to a uid that isn't in the export.
+type UnresolvedRef struct {
+	UID      string `json:"uid"`
+	Page     string `json:"page"`
+	BlockUID string `json:"block_uid"`
+}
+
+// addUnresolved records that the block with uid blockUID on the current page
+// references a missing uid. Pages that aren't written aren't reported.
+func (c *Converter) addUnresolved(uid, blockUID string) {
+	if c.pageSkipped {
+		return
+	}
+
+	ref := UnresolvedRef{UID: uid, Page: c.page.Title, BlockUID: blockUID}
+	if _, ok := c.unresolved[ref]; ok {
+		return
+	}
+
+	c.log.Warn("unresolved block reference", "uid", uid, "page", ref.Page, "block", blockUID)
+
+	c.unresolved[ref] = struct{}{}
+	c.referencedUID[blockUID] = struct{}{}
+	c.report.Unresolved = append(c.report.Unresolved, ref)
+}
+
+const (
+	// MissingRefsPlaceholder writes refs to blocks that aren't in the
+	// export as a tagged placeholder.
+	MissingRefsPlaceholder = "placeholder"
+	// MissingRefsKeep leaves them as written.
+	MissingRefsKeep = "keep"
+
+	missingRefTag = "#roam-missing-ref"
+)
+
+// missingRef is the placeholder for a ref to uid, a block that isn't in the
+// export, most likely because it was deleted.
+func missingRef(uid string) string {
+	return "[missing block: " + uid + "] " + missingRefTag
+}
+
+// Reasons a chain of block references was cut short.
+const (
+	RefChainCycle = "cycle"
+	RefChainDepth = "depth"
+)
+
+// RefChain is a chain of block references that was not expanded to its end,
+// because it leads back to a block already in it or goes deeper than
+// Options.MaxRefDepth. Path starts with the block being converted.
+type RefChain struct {
+	Page     string   `json:"page"`
+	BlockUID string   `json:"block_uid"`
+	Path     []string `json:"path"`
+	Reason   string   `json:"reason"`
+}
+
+// addRefChain records that expanding the refs of the block with uid blockUID
+// on the current page was cut short at the end of path.
+func (c *Converter) addRefChain(blockUID string, path []string, reason string) {
+	if c.pageSkipped {
+		return
+	}
+
+	key := c.page.Title + "\x00" + blockUID + "\x00" + reason
+	if _, ok := c.refChains[key]; ok {
+		return
+	}
+
+	c.log.Warn("block reference chain cut short", "reason", reason, "page", c.page.Title, "block", blockUID, "path", strings.Join(path, " -> "))
+
+	c.refChains[key] = struct{}{}
+	c.referencedUID[blockUID] = struct{}{}
+	c.report.RefChains = append(c.report.RefChains, RefChain{Page: c.page.Title, BlockUID: blockUID, Path: path, Reason: reason})
+}
+
+// strictError returns an error describing the problems that fail a strict
+// run, or nil if there are none.
+func (r *Report) strictError() error {
+	var problems []string
+	if n := len(r.Unresolved); n > 0 {
+		problems = append(problems, fmt.Sprintf("%d unresolved block references", n))
+	}
+	if n := len(r.RefChains); n > 0 {
+		problems = append(problems, fmt.Sprintf("%d block reference chains cut short", n))
+	}
+	if n := r.unresolvedCollisions(); n > 0 {
+		problems = append(problems, fmt.Sprintf("%d filename collisions", n))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("strict: %s", strings.Join(problems, ", "))
+}
+
+// unresolvedCollisions counts the collisions where one page overwrote another.
+func (r *Report) unresolvedCollisions() int {
+	n := 0
+	for _, col := range r.Collisions {
+		if len(col.Renamed) == 0 && col.Merged == "" {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (r *Report) empty() bool {
+	return len(r.Quarantined) == 0 && len(r.Collisions) == 0 && len(r.Skipped) == 0 &&
+		len(r.Unresolved) == 0 && len(r.RefChains) == 0 && len(r.Queries) == 0 &&
+		len(r.SmartBlocks) == 0
+}
+
+// write writes the report as a note in the vault and as JSON for tooling.
+// Nothing is written when there is nothing to report.
+func (r *Report) write(w vault.Writer) error {
+	if r.empty() {
+		return nil
+	}
+
+	var lines []string
+
+	if len(r.Quarantined) > 0 {
+		lines = append(lines, "## Quarantined pages", "")
+		for _, e := range r.Quarantined {
+			lines = append(lines, fmt.Sprintf("- [[%s]] (%s): %s", strings.TrimSuffix(e.Path, ".md"), e.Page, e.Reason))
+		}
+		lines = append(lines, "")
+	}
+
+	if len(r.Collisions) > 0 {
+		lines = append(lines, "## Filename collisions", "")
+		lines = append(lines, "These pages map to the same file on case-insensitive filesystems.", "")
+		for _, col := range r.Collisions {
+			var titles []string
+			for _, title := range col.Titles {
+				if renamed, ok := col.Renamed[title]; ok {
+					titles = append(titles, fmt.Sprintf("[[%s]] (renamed from %q)", renamed, title))
+					continue
+				}
+				if col.Merged != "" && title != col.Merged {
+					titles = append(titles, fmt.Sprintf("[[%s#%s|%s]] (merged into [[%s]])", col.Merged, title, title, col.Merged))
+					continue
+				}
+				titles = append(titles, fmt.Sprintf("[[%s]]", title))
+			}
+			lines = append(lines, "- "+strings.Join(titles, ", "))
+		}
+		lines = append(lines, "")
+	}
+
+	if len(r.Skipped) > 0 {
+		lines = append(lines, "## Skipped pages", "")
+		for _, e := range r.Skipped {
+			lines = append(lines, fmt.Sprintf("- %s (%s)", e.Page, e.Reason))
+		}
+		lines = append(lines, "")
+	}
+
+	if len(r.Unresolved) > 0 {
+		lines = append(lines, "## Unresolved block references", "")
+		for _, ref := range r.Unresolved {
+			lines = append(lines, fmt.Sprintf("- `%s` in [[%s#^%s]]", ref.UID, ref.Page, ref.BlockUID))
+		}
+		lines = append(lines, "")
+	}
+
+	if len(r.RefChains) > 0 {
+		lines = append(lines, "## Block reference chains cut short", "")
+		for _, chain := range r.RefChains {
+			lines = append(lines, fmt.Sprintf("- [[%s#^%s]] (%s): %s", chain.Page, chain.BlockUID, chain.Reason, strings.Join(chain.Path, " → ")))
+		}
+		lines = append(lines, "")
+	}
+
+	if len(r.Queries) > 0 {
+		lines = append(lines, "## Roam queries", "")
+		lines = append(lines, "These blocks hold datalog queries, which have to be migrated by hand.", "")
+		for _, q := range r.Queries {
+			query := strings.Join(strings.Fields(q.Query), " ")
+			if len([]rune(query)) > 80 {
+				query = string([]rune(query)[:80]) + "…"
+			}
+			if q.Stripped {
+				lines = append(lines, fmt.Sprintf("- [[%s]] (stripped): `%s`", q.Page, query))
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("- [[%s#^%s]]: `%s`", q.Page, q.BlockUID, query))
+		}
+		lines = append(lines, "")
+	}
+
+	if len(r.SmartBlocks) > 0 {
+		lines = append(lines, "## SmartBlocks", "")
+		lines = append(lines, "These blocks held SmartBlocks workflow syntax, which was cleaned up.", "")
+		for _, sb := range r.SmartBlocks {
+			found := make([]string, len(sb.Found))
+			for i, f := range sb.Found {
+				found[i] = "`" + f + "`"
+			}
+			lines = append(lines, fmt.Sprintf("- [[%s#^%s]]: %s", sb.Page, sb.BlockUID, strings.Join(found, ", ")))
+		}
+		lines = append(lines, "")
+	}
+
+	data := strings.Join(lines, "\n")
+
+	if err := w.WriteFile(reportTitle+".md", []byte(data)); err != nil {
+		return err
+	}
+
+	raw, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return w.WriteFile(reportJSON, append(raw, '\n'))
+}
@@ -0,0 +1,77 @@
+package convert
+
+import (
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	roamCSSPage = "roam/css"
+	roamJSPage  = "roam/js"
+
+	// roamJSDir holds the code of roam/js pages, kept for reference since
+	// Obsidian can't run it.
+	roamJSDir = "roam-js"
+
+	skipReasonRoamCode = "moved to code file"
+)
+
+// extractRoamCode writes the code blocks of roam/css pages, and pages below
+// them, to Obsidian CSS snippets and those of roam/js pages to roamJSDir.
+// The pages aren't written as notes. Snippets are left disabled, since Roam
+// CSS styles Roam's markup.
+func (c *Converter) extractRoamCode(pages []roam.Page) {
+	for i, page := range pages {
+		if _, ok := c.quarantined[i]; ok {
+			continue
+		}
+		if _, ok := c.skipped[i]; ok {
+			continue
+		}
+
+		var dir, ext string
+		var langs []string
+		switch {
+		case isRoamCodePage(page.Title, roamCSSPage):
+			dir, ext, langs = obsidianDir+"/"+snippetDir, "css", []string{"css"}
+		case isRoamCodePage(page.Title, roamJSPage):
+			dir, ext, langs = roamJSDir, "js", []string{"javascript", "js"}
+		default:
+			continue
+		}
+
+		code := pageCode(&page, langs)
+		if len(code) == 0 {
+			continue
+		}
+
+		rel := dir + "/" + strings.ReplaceAll(page.Title, "/", "-") + "." + ext
+		c.log.Debug("extract roam code", "page", page.Title, "path", rel)
+
+		c.snippets[rel] = strings.Join(code, "\n\n") + "\n"
+		c.skipped[i] = skipReasonRoamCode
+		c.report.Skipped = append(c.report.Skipped, ReportEntry{Page: page.Title, Path: rel, Reason: skipReasonRoamCode + " " + rel})
+	}
+}
+
+func isRoamCodePage(title, name string) bool {
+	return title == name || strings.HasPrefix(title, name+"/")
+}
+
+// pageCode returns the fenced code blocks of parent and its descendants
+// written in one of langs, or without a language.
+func pageCode(parent roam.Parent, langs []string) []string {
+	var code []string
+
+	for _, child := range parent.Children() {
+		body, lang := splitFence(child.String)
+		if body != child.String && (lang == "" || containsString(langs, strings.ToLower(lang))) {
+			code = append(code, body)
+		}
+
+		code = append(code, pageCode(&child, langs)...)
+	}
+
+	return code
+}
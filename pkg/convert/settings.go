@@ -0,0 +1,147 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/bryanl/goram2obs/pkg/vault"
+)
+
+const (
+	obsidianDir      = ".obsidian"
+	dailyNotesConfig = obsidianDir + "/daily-notes.json"
+
+	attachmentDir = "attachments"
+	templateDir   = "templates"
+)
+
+// scaffoldPlugins are the core plugins enabled in a new vault.
+var scaffoldPlugins = []string{
+	"file-explorer", "global-search", "switcher", "graph", "backlink",
+	"outgoing-link", "tag-pane", "page-preview", "daily-notes", "templates",
+	"note-composer", "command-palette", "outline",
+}
+
+// readSettings reads a settings file from the vault into v. A missing file,
+// or a writer that can't read back, leaves v untouched.
+func (c *Converter) readSettings(name string, v interface{}) error {
+	r, ok := c.w.(vault.Reader)
+	if !ok {
+		return nil
+	}
+
+	data, err := r.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("parse %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// mergeSettings sets values in the settings file name, keeping the settings
+// already in it.
+func (c *Converter) mergeSettings(name string, values map[string]interface{}) error {
+	settings := map[string]interface{}{}
+	if err := c.readSettings(name, &settings); err != nil {
+		return err
+	}
+
+	for k, v := range values {
+		settings[k] = v
+	}
+
+	return c.writeSettings(name, settings)
+}
+
+func (c *Converter) writeSettings(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return c.w.WriteFile(name, append(data, '\n'))
+}
+
+// writeDailyNotesConfig points the Daily Notes core plugin at the converted
+// daily notes.
+func (c *Converter) writeDailyNotesConfig() error {
+	format := "YYYY-MM-DD"
+	if c.opts.DayStyle == DayStyleRoam {
+		format = "MMMM Do, YYYY"
+	}
+
+	return c.mergeSettings(dailyNotesConfig, map[string]interface{}{
+		"folder": "daily",
+		"format": format,
+	})
+}
+
+// initVault writes the .obsidian settings that make the output a ready to use
+// vault: the attachment folder, the templates folder, daily notes and the
+// core plugins they need.
+func (c *Converter) initVault() error {
+	err := c.mergeSettings(obsidianDir+"/app.json", map[string]interface{}{
+		"attachmentFolderPath": attachmentDir,
+		"alwaysUpdateLinks":    true,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := c.enableCorePlugins(scaffoldPlugins); err != nil {
+		return err
+	}
+
+	err = c.mergeSettings(obsidianDir+"/templates.json", map[string]interface{}{
+		"folder": templateDir,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.writeDailyNotesConfig()
+}
+
+// enableCorePlugins adds plugins to the enabled core plugins. Older vaults
+// list the enabled plugins, newer ones map every plugin to whether it is
+// enabled; the vault's format is kept.
+func (c *Converter) enableCorePlugins(plugins []string) error {
+	const name = obsidianDir + "/core-plugins.json"
+
+	var existing interface{}
+	if err := c.readSettings(name, &existing); err != nil {
+		return err
+	}
+
+	if enabled, ok := existing.(map[string]interface{}); ok {
+		for _, plugin := range plugins {
+			enabled[plugin] = true
+		}
+		return c.writeSettings(name, enabled)
+	}
+
+	var list []string
+	if items, ok := existing.([]interface{}); ok {
+		for _, item := range items {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+	}
+	for _, plugin := range plugins {
+		if !containsString(list, plugin) {
+			list = append(list, plugin)
+		}
+	}
+
+	return c.writeSettings(name, list)
+}
@@ -0,0 +1,182 @@
+package convert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	ShardMergeDailies     = "merge-dailies"
+	ShardMergeDailiesYear = "merge-dailies-year"
+	ShardPruneEmpty       = "prune-empty"
+)
+
+var ShardStrategies = []string{ShardMergeDailies, ShardMergeDailiesYear, ShardPruneEmpty}
+
+// mergedSection is a page written as a section of a merged note.
+type mergedSection struct {
+	date  time.Time
+	title string
+	lines []string
+	// front is frontmatter written at the top of the merged note.
+	front []string
+}
+
+// shard keeps the number of notes at or below maxFiles by applying the given
+// strategies in order, stopping once the output is small enough. If the
+// output is still too large a warning suggests the strategies not yet used.
+func (c *Converter) shard(pages []roam.Page, maxFiles int, strategies []string) {
+	if maxFiles <= 0 {
+		return
+	}
+
+	count := c.outputCount(pages)
+	for _, strategy := range strategies {
+		if count <= maxFiles {
+			return
+		}
+
+		switch strategy {
+		case ShardMergeDailies:
+			c.mergeDailies(pages, "2006-01", "January 2006")
+		case ShardMergeDailiesYear:
+			c.mergeDailies(pages, "2006", "2006")
+		case ShardPruneEmpty:
+			c.skipPages(pages, true, false)
+		}
+
+		next := c.outputCount(pages)
+		c.log.Info("applied shard strategy", "strategy", strategy, "before", count, "after", next)
+		count = next
+	}
+
+	if count <= maxFiles {
+		return
+	}
+
+	var unused []string
+	for _, strategy := range ShardStrategies {
+		if !containsString(strategies, strategy) {
+			unused = append(unused, strategy)
+		}
+	}
+
+	c.log.Warn("conversion writes more notes than -max-files", "notes", count, "max", maxFiles)
+	if len(unused) > 0 {
+		c.log.Warn("more shard strategies can reduce the number of notes", "shard", strings.Join(unused, ","))
+	}
+}
+
+// outputCount returns the number of notes that will be written.
+func (c *Converter) outputCount(pages []roam.Page) int {
+	files := map[string]struct{}{}
+	for i := range pages {
+		if _, ok := c.quarantined[i]; ok {
+			files[fmt.Sprintf("quarantine-%d", i)] = struct{}{}
+			continue
+		}
+
+		if _, ok := c.skipped[i]; ok || pages[i].Title == "" {
+			continue
+		}
+
+		dest := c.pagePath(&pages[i])
+		if merged, ok := c.merged[pages[i].Title]; ok {
+			dest = c.mergedPath(merged)
+		}
+		files[strings.ToLower(dest)] = struct{}{}
+	}
+
+	return len(files)
+}
+
+// mergeDailies assigns every daily note to a merged note named after the
+// period it falls in. obsLayout and roamLayout format the merged note title
+// for the obsidian and roam day styles.
+func (c *Converter) mergeDailies(pages []roam.Page, obsLayout, roamLayout string) {
+	for i, page := range pages {
+		if _, ok := c.skipped[i]; ok || !page.IsDaily {
+			continue
+		}
+
+		t, ok := c.parseDaily(page.Title)
+		if !ok {
+			continue
+		}
+
+		layout := obsLayout
+		if c.opts.DayStyle == DayStyleRoam {
+			layout = roamLayout
+		}
+		c.merged[page.Title] = t.Format(layout)
+	}
+}
+
+// parseDaily parses a converted daily note title.
+func (c *Converter) parseDaily(title string) (time.Time, bool) {
+	if c.opts.DayStyle == DayStyleRoam {
+		t, ok, err := roam.ParseDate(title)
+		return t, ok && err == nil
+	}
+
+	t, err := time.Parse(obsDailyLayout, title)
+	return t, err == nil
+}
+
+func (c *Converter) addMergedSection(title string, lines []string) {
+	merged := c.merged[title]
+	t, _ := c.parseDaily(title)
+	c.mergedSections[merged] = append(c.mergedSections[merged], mergedSection{date: t, title: title, lines: lines})
+}
+
+// mergedPath returns the vault path of the merged daily note titled merged,
+// without the file extension.
+func (c *Converter) mergedPath(merged string) string {
+	return c.dailyFolder() + merged
+}
+
+// writeMerged writes the merged daily notes with one section per day, in
+// the order of their titles.
+func (c *Converter) writeMerged() error {
+	titles := make([]string, 0, len(c.mergedSections))
+	for merged := range c.mergedSections {
+		titles = append(titles, merged)
+	}
+	sort.Strings(titles)
+
+	for _, merged := range titles {
+		sections := c.mergedSections[merged]
+		sort.Slice(sections, func(i, j int) bool {
+			return sections[i].date.Before(sections[j].date)
+		})
+
+		var lines []string
+		for _, section := range sections {
+			lines = append(lines, "## "+section.title)
+			lines = append(lines, section.lines...)
+			lines = append(lines, "")
+		}
+
+		dest := c.mergedPath(merged) + ".md"
+		if err := c.w.WriteFile(dest, []byte(strings.Join(lines, "\n"))); err != nil {
+			return err
+		}
+		c.addNote(dest, sections[0].date)
+	}
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,60 @@
+package convert
+
+import (
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	skipReasonEmpty  = "empty"
+	skipReasonOrphan = "orphan"
+)
+
+// skipPages marks empty pages and, optionally, orphan pages so they aren't
+// written. An orphan is a non-daily page that no other page links to.
+func (c *Converter) skipPages(pages []roam.Page, skipEmpty, skipOrphans bool) {
+	if !skipEmpty && !skipOrphans {
+		return
+	}
+
+	var linked map[string]int
+	if skipOrphans {
+		linked = c.linkedTitles(pages)
+	}
+
+	for i := range pages {
+		page := &pages[i]
+		if _, ok := c.quarantined[i]; ok || page.Title == "" {
+			continue
+		}
+		if _, ok := c.skipped[i]; ok {
+			continue
+		}
+
+		reason := ""
+		switch {
+		case skipEmpty && isEmpty(page):
+			reason = skipReasonEmpty
+		case skipOrphans && !page.IsDaily && linked[page.Title] == 0:
+			reason = skipReasonOrphan
+		default:
+			continue
+		}
+
+		c.log.Debug("skip page", "page", page.Title, "reason", reason)
+		c.skipped[i] = reason
+		c.report.Skipped = append(c.report.Skipped, ReportEntry{Page: page.Title, Reason: reason})
+	}
+}
+
+// isEmpty reports whether parent has no blocks with any text.
+func isEmpty(parent roam.Parent) bool {
+	for _, child := range parent.Children() {
+		if strings.TrimSpace(child.String) != "" || !isEmpty(&child) {
+			return false
+		}
+	}
+
+	return true
+}
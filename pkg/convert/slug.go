@@ -0,0 +1,102 @@
+package convert
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"unicode"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// slugTitles gives every page that isn't a daily note a kebab-case ASCII
+// filename. Namespaces stay folders. The original title is kept in the
+// frontmatter and as an alias, and links use it as their text.
+func (c *Converter) slugTitles(pages []roam.Page) {
+	for i, page := range pages {
+		if _, ok := c.quarantined[i]; ok || page.IsDaily || page.Title == "" {
+			continue
+		}
+
+		title := c.fileTitle(page.Title)
+		slug := slugTitle(title)
+		if slug == title {
+			continue
+		}
+
+		c.log.Debug("slugify title", "page", page.Title, "title", slug)
+
+		c.renamed[page.Title] = slug
+		if !containsString(c.aliases[page.Title], page.Title) {
+			c.aliases[page.Title] = append(c.aliases[page.Title], page.Title)
+		}
+	}
+}
+
+// slugTitle slugifies every namespace part of title.
+func slugTitle(title string) string {
+	parts := strings.Split(title, "/")
+	for i, part := range parts {
+		parts[i] = slugify(part)
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// slugify lowercases s, folds accented Latin letters to ASCII and joins the
+// remaining runs of letters and digits with hyphens. A name without any of
+// them gets one derived from its hash.
+func slugify(s string) string {
+	var sb strings.Builder
+	dash := false
+
+	for _, r := range strings.ToLower(s) {
+		if folded, ok := asciiFold[r]; ok {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteString(folded)
+			dash = false
+			continue
+		}
+
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			dash = false
+			continue
+		}
+
+		// apostrophes don't split words
+		if r != '\'' && r != '’' {
+			dash = true
+		}
+	}
+
+	if sb.Len() == 0 {
+		sum := sha1.Sum([]byte(s))
+		return "page-" + hex.EncodeToString(sum[:4])
+	}
+
+	return sb.String()
+}
+
+// asciiFold maps lowercase Latin letters with diacritics to ASCII.
+var asciiFold = map[rune]string{}
+
+func init() {
+	pairs := []string{
+		"àáâãäåāăą", "a", "æ", "ae", "çćĉċč", "c", "ďđ", "d",
+		"èéêëēĕėęě", "e", "ĝğġģ", "g", "ĥħ", "h", "ìíîïĩīĭįı", "i",
+		"ĵ", "j", "ķ", "k", "ĺļľŀł", "l", "ñńņňŉ", "n", "òóôõöøōŏő", "o",
+		"œ", "oe", "ŕŗř", "r", "śŝşš", "s", "ß", "ss", "ţťŧ", "t",
+		"ùúûüũūŭůűų", "u", "ŵ", "w", "ýÿŷ", "y", "źżž", "z", "þ", "th", "ð", "d",
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		for _, r := range pairs[i] {
+			asciiFold[r] = pairs[i+1]
+		}
+	}
+}
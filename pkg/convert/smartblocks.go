@@ -0,0 +1,131 @@
+package convert
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	SmartBlocksKeep  = "keep"
+	SmartBlocksStrip = "strip"
+	SmartBlocksText  = "text"
+)
+
+// SmartBlock is a block that held SmartBlocks syntax, which doesn't work
+// outside Roam.
+type SmartBlock struct {
+	Page     string `json:"page"`
+	BlockUID string `json:"block_uid"`
+	// Found is the SmartBlocks syntax in the block, as written.
+	Found []string `json:"found"`
+}
+
+// smartBlocks cleans the SmartBlocks syntax out of the text s of block:
+// workflow buttons, <%COMMAND%> placeholders and the #42SmartBlock tag that
+// marks a workflow. Under SmartBlocksStrip all of it is removed. Under
+// SmartBlocksText buttons become their label and commands inline code.
+func (c *Converter) smartBlocks(s string, block *roam.Child) string {
+	if c.opts.SmartBlocks == SmartBlocksKeep || !strings.Contains(s, "SmartBlock") && !strings.Contains(s, "<%") {
+		return s
+	}
+
+	var found []string
+	text := c.opts.SmartBlocks == SmartBlocksText
+
+	// removed syntax leaves a marker, so the spaces around it can be
+	// tidied up once everything is removed
+	const removed = "\x00"
+
+	s = outsideCode(s, func(s string) string {
+		s = reSmartBlockButton.ReplaceAllStringFunc(s, func(button string) string {
+			found = append(found, button)
+			if !text {
+				return removed
+			}
+
+			m := reSmartBlockButton.FindStringSubmatch(button)
+			switch {
+			case m[1] != "":
+				return strings.TrimSpace(m[1])
+			case m[4] != "":
+				return strings.TrimSpace(m[4])
+			case m[3] != "":
+				return strings.TrimSpace(m[3])
+			}
+			return strings.TrimSpace(m[2])
+		})
+
+		s = reSmartBlockCommand.ReplaceAllStringFunc(s, func(cmd string) string {
+			found = append(found, cmd)
+			if text {
+				return "`" + cmd + "`"
+			}
+			return removed
+		})
+
+		s = reSmartBlockTag.ReplaceAllStringFunc(s, func(tag string) string {
+			found = append(found, strings.TrimSpace(tag))
+			return removed
+		})
+
+		return s
+	})
+
+	if len(found) == 0 {
+		return s
+	}
+
+	c.recordSmartBlock(block, found)
+
+	return squeezeRemoved(s)
+}
+
+// squeezeRemoved drops the markers left by removed syntax and the spaces
+// around them, keeping a single space between the words on either side.
+func squeezeRemoved(s string) string {
+	var sb strings.Builder
+	last := 0
+
+	for _, m := range reRemoved.FindAllStringIndex(s, -1) {
+		sb.WriteString(s[last:m[0]])
+		last = m[1]
+
+		atStart := m[0] == 0 || s[m[0]-1] == '\n'
+		atEnd := m[1] == len(s) || s[m[1]] == '\n'
+		if !atStart && !atEnd {
+			sb.WriteString(" ")
+		}
+	}
+	sb.WriteString(s[last:])
+
+	return sb.String()
+}
+
+func (c *Converter) recordSmartBlock(block *roam.Child, found []string) {
+	if !c.writing {
+		return
+	}
+	if _, ok := c.smartBlockUIDs[block.UID]; ok {
+		return
+	}
+	c.smartBlockUIDs[block.UID] = struct{}{}
+
+	page := c.page.Title
+	if block.Page != nil {
+		page = block.Page.Title
+	}
+
+	c.referencedUID[block.UID] = struct{}{}
+	c.report.SmartBlocks = append(c.report.SmartBlocks, SmartBlock{Page: page, BlockUID: block.UID, Found: found})
+}
+
+var (
+	// reSmartBlockButton matches {{label:SmartBlock:workflow}} buttons and
+	// the older {{[[SmartBlock]]:workflow:label}} ones.
+	reSmartBlockButton  = regexp.MustCompile(`{{\s*(?:([^:{}]*):\s*SmartBlock\s*:([^{}:]*)(?::[^{}]*)?|(?:\[\[SmartBlock\]\]|SmartBlock)\s*:([^{}:]*)(?::([^{}]*))?)}}`)
+	reSmartBlockCommand = regexp.MustCompile(`<%[A-Z][A-Z0-9_]*(?::[^%]*)?%>`)
+	reSmartBlockTag     = regexp.MustCompile(`(?:^|\s)#(?:\[\[)?42SmartBlock(?:\]\])?\b`)
+	reRemoved           = regexp.MustCompile(`[ \t]*(?:\x00[ \t]*)+`)
+)
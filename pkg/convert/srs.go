@@ -0,0 +1,47 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+const (
+	SRSKeep       = "keep"
+	SRSFlashcards = "flashcards"
+
+	// srsDeckTag is the Spaced Repetition plugin's default flashcard tag.
+	srsDeckTag = "#flashcards"
+	// srsEase is the ease the Spaced Repetition plugin starts cards at.
+	srsEase = 250
+)
+
+// srsItem rewrites a {{[[∆]]: n+m}} spaced repetition button, which Roam
+// uses to bring a block back n days after it was last reviewed, into the
+// Spaced Repetition plugin's flashcard tag and scheduling comment. The card
+// is due n days after the block was last edited.
+func (c *Converter) srsItem(call componentCall) (string, bool) {
+	if c.opts.SRS != SRSFlashcards {
+		return "", false
+	}
+
+	m := reSRSInterval.FindStringSubmatch(call.Args)
+	if m == nil {
+		return "", false
+	}
+	interval, err := strconv.Atoi(m[1])
+	if err != nil {
+		return "", false
+	}
+
+	block, ok := c.uidBlock[call.BlockUID]
+	if !ok || block.EditTime.IsZero() {
+		return srsDeckTag, true
+	}
+
+	due := c.localTime(block.EditTime).AddDate(0, 0, interval)
+
+	return fmt.Sprintf("%s <!--SR:!%s,%d,%d-->", srsDeckTag, due.Format("2006-01-02"), interval, srsEase), true
+}
+
+var reSRSInterval = regexp.MustCompile(`^(\d+)\s*\+\s*\d+$`)
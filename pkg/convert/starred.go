@@ -0,0 +1,75 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const starredTitle = "Starred"
+
+// collectStarred records the starred blocks of pages, in page order, and
+// marks them as referenced so they can be embedded.
+func (c *Converter) collectStarred(pages []roam.Page) {
+	var walk func(i int, children []roam.Child)
+	walk = func(i int, children []roam.Child) {
+		for _, child := range children {
+			if child.Starred {
+				c.starred = append(c.starred, starredBlock{page: i, uid: child.UID})
+				c.referencedUID[child.UID] = struct{}{}
+			}
+			walk(i, child.RawChildren)
+		}
+	}
+
+	for i := range pages {
+		walk(i, pages[i].RawChildren)
+	}
+}
+
+// starredBlock is a starred block of the page at index page.
+type starredBlock struct {
+	page int
+	uid  string
+}
+
+// writeStarred writes a note embedding every starred block.
+func (c *Converter) writeStarred(pages []roam.Page) error {
+	if len(c.starred) == 0 {
+		return nil
+	}
+
+	for _, page := range pages {
+		if page.Title == starredTitle {
+			c.log.Warn("a page is already named Starred, starred blocks are not collected", "blocks", len(c.starred))
+			return nil
+		}
+	}
+
+	var lines []string
+	for _, block := range c.starred {
+		if _, ok := c.skipped[block.page]; ok {
+			continue
+		}
+		if _, ok := c.quarantined[block.page]; ok {
+			continue
+		}
+
+		if c.logseq() {
+			lines = append(lines, "- "+logseqBlockRef(block.uid, true))
+			continue
+		}
+
+		line, err := c.replaceDayLinks(fmt.Sprintf("![[%s#^%s]]", pages[block.page].Title, block.uid))
+		if err != nil {
+			return err
+		}
+		lines = append(lines, c.rewriteLinks(line), "")
+	}
+
+	data := strings.TrimSpace(strings.Join(lines, "\n")) + "\n"
+	note := roam.Page{Title: starredTitle}
+
+	return c.w.WriteFile(c.pagePath(&note)+".md", []byte(data))
+}
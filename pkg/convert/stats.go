@@ -0,0 +1,32 @@
+package convert
+
+// Stats summarizes a conversion.
+type Stats struct {
+	// Pages is the number of pages converted, including daily notes.
+	Pages      int `json:"pages"`
+	DailyNotes int `json:"daily_notes"`
+	Skipped    int `json:"skipped"`
+	// Quarantined is the number of pages that failed to convert.
+	Quarantined int `json:"quarantined"`
+
+	Blocks              int `json:"blocks"`
+	BlockRefsResolved   int `json:"block_refs_resolved"`
+	BlockRefsUnresolved int `json:"block_refs_unresolved"`
+	Tags                int `json:"tags"`
+
+	// Assets is the number of files other than notes written into the
+	// vault: drawings, code snippets, canvases and flashcards.
+	Assets   int `json:"assets"`
+	Warnings int `json:"warnings"`
+}
+
+// countingLogger counts the warnings logged during a conversion.
+type countingLogger struct {
+	Logger
+	warnings *int
+}
+
+func (l countingLogger) Warn(msg string, kv ...interface{}) {
+	*l.warnings++
+	l.Logger.Warn(msg, kv...)
+}
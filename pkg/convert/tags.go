@@ -0,0 +1,98 @@
+package convert
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	SlashTagsTag  = "tag"
+	SlashTagsLink = "link"
+	SlashTagsBoth = "both"
+)
+
+// slashTags rewrites tags with a slash, such as #projects/alpha, which are
+// nested tags in Obsidian but namespaced pages in Roam. SlashTagsLink turns
+// them into a link to the page and SlashTagsBoth adds the link after the tag.
+func (c *Converter) slashTags(s string) string {
+	if c.opts.SlashTags == SlashTagsTag || !strings.Contains(s, "/") {
+		return s
+	}
+
+	return reTagToken.ReplaceAllStringFunc(s, func(token string) string {
+		m := reTagToken.FindStringSubmatch(token)
+		name := strings.TrimPrefix(m[2], "#")
+		if strings.HasPrefix(name, "[[") {
+			name = name[2 : len(name)-2]
+		}
+		if !strings.Contains(strings.Trim(name, "/"), "/") {
+			return token
+		}
+
+		link := "[[" + name + "]]"
+		if c.opts.SlashTags == SlashTagsBoth {
+			return m[1] + m[2] + " " + link
+		}
+
+		return m[1] + link
+	})
+}
+
+// moveTagsToEnd moves the #tags and #[[tags]] in s to the end of the block,
+// in order of first use and without duplicates. Tags in code stay put.
+func moveTagsToEnd(s string) string {
+	spans := reCode.FindAllStringIndex(s, -1)
+
+	var matches [][]int
+	for _, m := range reTagToken.FindAllStringSubmatchIndex(s, -1) {
+		if !inCode(spans, m[4], m[5]) {
+			matches = append(matches, m)
+		}
+	}
+	if len(matches) == 0 {
+		return s
+	}
+
+	var sb strings.Builder
+	var tags []string
+	seen := map[string]struct{}{}
+	last := 0
+
+	for _, m := range matches {
+		tag := s[m[4]:m[5]]
+		sb.WriteString(s[last:m[4]])
+		last = m[5]
+
+		key := strings.ToLower(tag)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		tags = append(tags, tag)
+	}
+	sb.WriteString(s[last:])
+
+	// the space left by the tags is tidied outside code only, so code
+	// keeps its indentation
+	text := outsideCode(sb.String(), func(text string) string {
+		text = reSpaceRun.ReplaceAllString(text, " ")
+		return reTrailingSpace.ReplaceAllString(text, "\n")
+	})
+	text = strings.TrimLeft(strings.TrimRight(text, " \t\n"), " \t")
+
+	switch {
+	case text == "":
+		return strings.Join(tags, " ")
+	case endsWithFence(text):
+		// tags after the closing fence would stop it closing the code
+		return text + "\n" + strings.Join(tags, " ")
+	}
+
+	return text + " " + strings.Join(tags, " ")
+}
+
+var (
+	reTagToken      = regexp.MustCompile(`(^|\s)(#\[\[[^\[\]]+\]\]|#[\w/-]+)`)
+	reSpaceRun      = regexp.MustCompile(`[ \t]{2,}`)
+	reTrailingSpace = regexp.MustCompile(`[ \t]+\n`)
+)
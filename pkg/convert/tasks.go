@@ -0,0 +1,66 @@
+package convert
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	TasksKeep   = "keep"
+	TasksPlugin = "plugin"
+
+	// tasksDue and tasksDone are the signifiers the Tasks plugin reads due
+	// and done dates from.
+	tasksDue  = "📅"
+	tasksDone = "✅"
+)
+
+// taskText rewrites a block starting with a {{[[TODO]]}} or {{[[DONE]]}}
+// marker as an Obsidian Tasks checkbox. The first daily note link in the
+// block, with an "at" or "on" before it, becomes the task's due date, or
+// its done date when it is done. It reports whether s was a task.
+func (c *Converter) taskText(s string) (string, bool) {
+	if c.opts.Tasks != TasksPlugin {
+		return s, false
+	}
+
+	m := reTaskMarker.FindStringSubmatch(s)
+	if m == nil {
+		return s, false
+	}
+	done := strings.EqualFold(m[1], "DONE")
+	s = s[len(m[0]):]
+
+	date := ""
+	if loc := reTaskDate.FindStringSubmatchIndex(s); loc != nil {
+		if t, ok, err := roam.ParseDate(s[loc[2]:loc[3]]); err == nil && ok {
+			date = t.Format(obsDailyLayout)
+			s = s[:loc[0]] + " " + s[loc[1]:]
+		}
+	}
+
+	// the dates follow the first line, where the plugin looks for them
+	first, rest := s, ""
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		first, rest = s[:i], s[i:]
+	}
+	first = strings.TrimSpace(reSpaceRun.ReplaceAllString(first, " "))
+
+	box := "[ ] "
+	signifier := tasksDue
+	if done {
+		box, signifier = "[x] ", tasksDone
+	}
+	if date != "" {
+		first += " " + signifier + " " + date
+	}
+
+	return box + strings.TrimSpace(first) + rest, true
+}
+
+var (
+	reTaskMarker = regexp.MustCompile(`^\{\{\s*(?:\[\[)?(?i:(TODO|DONE))(?:\]\])?\s*\}\}\s*`)
+	reTaskDate   = regexp.MustCompile(`(?:\s*\b(?i:at|on)\s+|\s*)\[\[(` + roam.DatePattern + `)\]\]`)
+)
@@ -0,0 +1,98 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	templatesPage = "roam/templates"
+
+	skipReasonTemplates = "moved to " + templateDir
+)
+
+// reservedNameChars can't be used in filenames on every platform Obsidian
+// runs on.
+var reservedNameChars = strings.NewReplacer(
+	"/", "-", "\\", "-", ":", "", "*", "", "?", "", "\"", "", "<", "", ">", "", "|", "-", "#", "", "^", "",
+)
+
+// skipTemplatesPage leaves the roam/templates page out of the notes. Its
+// templates are written by writeTemplates.
+func (c *Converter) skipTemplatesPage(pages []roam.Page) {
+	for i, page := range pages {
+		if _, ok := c.quarantined[i]; ok || page.Title != templatesPage {
+			continue
+		}
+		if _, ok := c.skipped[i]; ok {
+			continue
+		}
+
+		c.log.Debug("skip page", "page", page.Title, "reason", skipReasonTemplates)
+		c.skipped[i] = skipReasonTemplates
+		c.report.Skipped = append(c.report.Skipped, ReportEntry{Page: page.Title, Reason: skipReasonTemplates})
+		c.templates = &pages[i]
+	}
+}
+
+// writeTemplates writes every template on the roam/templates page, a block
+// named after the template and its children, to its own note in the
+// templates folder, and points the Templates core plugin at the folder.
+func (c *Converter) writeTemplates() error {
+	if c.templates == nil {
+		return nil
+	}
+
+	c.page = c.templates
+	written := map[string]struct{}{}
+
+	for _, child := range c.templates.Children() {
+		if len(child.Children()) == 0 {
+			continue
+		}
+
+		name := templateName(&child)
+		for n := 2; ; n++ {
+			if _, ok := written[strings.ToLower(name)]; !ok {
+				break
+			}
+			name = fmt.Sprintf("%s %d", templateName(&child), n)
+		}
+		written[strings.ToLower(name)] = struct{}{}
+
+		lines, err := c.expandChildren(&child, 0)
+		if err != nil {
+			return fmt.Errorf("template %q: %w", name, err)
+		}
+
+		rel := templateDir + "/" + name + ".md"
+		c.log.Debug("write template", "path", rel)
+		if err := c.w.WriteFile(rel, []byte(strings.Join(lines, "\n"))); err != nil {
+			return err
+		}
+	}
+
+	err := c.mergeSettings(obsidianDir+"/templates.json", map[string]interface{}{
+		"folder": templateDir,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.enableCorePlugins([]string{"templates"})
+}
+
+// templateName returns the filename of the template defined by block: its
+// text without links or characters filenames can't hold, or its uid when
+// nothing is left.
+func templateName(block *roam.Child) string {
+	name := reservedNameChars.Replace(stripBrackets(block.String))
+	name = truncateName(strings.Join(strings.Fields(name), " "), mobileMaxName)
+	if name == "" {
+		return block.UID
+	}
+
+	return name
+}
@@ -0,0 +1,76 @@
+package convert
+
+import (
+	"encoding/json"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const uidMapFile = "uid-map.json"
+
+// UIDMap records where the pages and blocks of the graph ended up, so tools
+// that store Roam UIDs can be pointed at the vault.
+type UIDMap struct {
+	// Pages maps Roam page titles to note paths.
+	Pages map[string]string `json:"pages"`
+	// Blocks maps block UIDs to a note path and the anchor of the block.
+	Blocks map[string]string `json:"blocks"`
+}
+
+// recordTitles remembers the Roam title of every page before conversion
+// renames daily notes and other pages.
+func (c *Converter) recordTitles(pages []roam.Page) {
+	c.roamTitles = make([]string, len(pages))
+	for i := range pages {
+		c.roamTitles[i] = pages[i].Title
+	}
+}
+
+// anchorAllBlocks gives every block an anchor, so each block in the UID map
+// can be linked to.
+func (c *Converter) anchorAllBlocks() {
+	for uid := range c.uidBlock {
+		c.referencedUID[uid] = struct{}{}
+	}
+}
+
+// mapPage adds page i and its blocks, written to dest, to the UID map.
+func (c *Converter) mapPage(i int, page *roam.Page, dest string) {
+	if merged, ok := c.merged[page.Title]; ok {
+		dest = c.mergedPath(merged) + ".md"
+	}
+	if first, ok := c.collided[page.Title]; ok {
+		dest = c.pagePath(&roam.Page{Title: first, IsDaily: page.IsDaily}) + ".md"
+	}
+
+	c.uidMap.Pages[c.roamTitles[i]] = dest
+	c.mapBlocks(page, dest)
+}
+
+func (c *Converter) mapBlocks(parent roam.Parent, dest string) {
+	for _, child := range parent.Children() {
+		c.uidMap.Blocks[child.UID] = dest + c.blockAnchor(child.UID)
+		c.mapBlocks(&child, dest)
+	}
+}
+
+// blockAnchor returns the fragment that links to the block with uid.
+func (c *Converter) blockAnchor(uid string) string {
+	switch {
+	case c.opts.Target == TargetHTML:
+		return "#" + uid
+	case c.logseq():
+		return "#" + logseqBlockID(uid)
+	default:
+		return "#^" + uid
+	}
+}
+
+func (c *Converter) writeUIDMap() error {
+	raw, err := json.MarshalIndent(c.uidMap, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return c.w.WriteFile(uidMapFile, append(raw, '\n'))
+}
@@ -0,0 +1,136 @@
+package convert
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+const (
+	WidgetStrip = "strip"
+	WidgetText  = "text"
+	WidgetKeep  = "keep"
+	WidgetHTML  = "html"
+)
+
+// widget is an interactive Roam component that has no Obsidian equivalent.
+type widget struct {
+	// Mode is what is done with the widget by default.
+	Mode string
+	// Text is the text written in WidgetText mode. Widgets without it
+	// can only be stripped or kept.
+	Text func(call componentCall) string
+	// HTML is the HTML written in WidgetHTML mode, for widgets that have
+	// an HTML embed.
+	HTML func(call componentCall) string
+}
+
+// widgets are the known widgets, by name.
+var widgets = map[string]widget{
+	"word-count":      {Mode: WidgetStrip},
+	"character-count": {Mode: WidgetStrip},
+	"slider":          {Mode: WidgetStrip},
+	"POMO": {Mode: WidgetText, Text: func(call componentCall) string {
+		if call.Args == "" {
+			return "🍅"
+		}
+		return "🍅 " + call.Args + " min"
+	}},
+	"calc": {Mode: WidgetText, Text: func(call componentCall) string {
+		return "`" + call.Args + "`"
+	}},
+	"tweet": {Mode: WidgetText, Text: embedURL, HTML: func(call componentCall) string {
+		url := html.EscapeString(embedURL(call))
+		return `<blockquote class="twitter-tweet"><a href="` + url + `">` + url + `</a></blockquote>`
+	}},
+}
+
+// embedURL returns the URL a component embeds, which may be written as a
+// Markdown link.
+func embedURL(call componentCall) string {
+	url := strings.TrimSpace(call.Args)
+	if m := reMarkdownURL.FindStringSubmatch(url); m != nil {
+		url = m[1]
+	}
+
+	return url
+}
+
+// WidgetNames returns the names of the known widgets.
+func WidgetNames() []string {
+	names := make([]string, 0, len(widgets))
+	for name := range widgets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func init() {
+	for name := range widgets {
+		components.register(componentSpec{
+			Name:   name,
+			Handle: (*Converter).widget,
+		})
+	}
+}
+
+// widget writes a widget as set in Options.Widgets, or its default.
+func (c *Converter) widget(call componentCall) (string, bool) {
+	name, w := lookupWidget(call.Name)
+
+	mode := w.Mode
+	for set, m := range c.opts.Widgets {
+		if strings.EqualFold(set, name) {
+			mode = m
+		}
+	}
+
+	switch mode {
+	case WidgetStrip:
+		return "", true
+	case WidgetText:
+		return w.Text(call), true
+	case WidgetHTML:
+		return w.HTML(call), true
+	default:
+		return "", false
+	}
+}
+
+func lookupWidget(name string) (string, widget) {
+	for known, w := range widgets {
+		if strings.EqualFold(known, name) {
+			return known, w
+		}
+	}
+
+	return "", widget{}
+}
+
+func validateWidgets(modes map[string]string) error {
+	for name, mode := range modes {
+		known, w := lookupWidget(name)
+		if known == "" {
+			return fmt.Errorf("unknown widget %q", name)
+		}
+
+		switch mode {
+		case WidgetStrip, WidgetKeep:
+		case WidgetText:
+			if w.Text == nil {
+				return fmt.Errorf("widget %q has no text to write", name)
+			}
+		case WidgetHTML:
+			if w.HTML == nil {
+				return fmt.Errorf("widget %q has no HTML to write", name)
+			}
+		default:
+			return fmt.Errorf("unknown mode %q for widget %q", mode, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package convert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+const (
+	WrapperFence   = "fence"
+	WrapperSnippet = "snippet"
+
+	snippetDir = "snippets"
+)
+
+// codeWrapper describes a component whose children hold source code, such as
+// {{mermaid}} or {{[[htmlview]]}}.
+type codeWrapper struct {
+	// Lang is the language tag of the fenced code block.
+	Lang string
+	// Ext is the extension used when the code is written to a snippet file.
+	// Wrappers without one are always rendered as fenced code.
+	Ext string
+}
+
+// wrapperFor returns the code wrapper for a block that consists of a single
+// component, if the component is a code wrapper.
+func wrapperFor(s string) (*codeWrapper, bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{{") || componentEnd(s, 0) != len(s) {
+		return nil, false
+	}
+
+	w := components.wrapper(parseComponentCall(s))
+
+	return w, w != nil
+}
+
+// wrappedSource reassembles the code held by the children of a code wrapper.
+// Nested children are indented below their parent. The language of the first
+// fenced child, if any, is returned as well.
+func wrappedSource(parent roam.Parent, depth int) ([]string, string) {
+	var lines []string
+	lang := ""
+
+	for _, child := range parent.Children() {
+		body, childLang := splitFence(child.String)
+		if lang == "" {
+			lang = childLang
+		}
+
+		indent := strings.Repeat("    ", depth)
+		for _, line := range strings.Split(body, "\n") {
+			lines = append(lines, indent+line)
+		}
+
+		nested, _ := wrappedSource(&child, depth+1)
+		lines = append(lines, nested...)
+	}
+
+	return lines, lang
+}
+
+// expandWrapper renders a code wrapper block as fenced code, or in snippet
+// mode writes the code to a snippet file and links to it.
+func (c *Converter) expandWrapper(w *codeWrapper, child *roam.Child, prefix, indent string) []string {
+	body, lang := wrappedSource(child, 0)
+	if lang == "" {
+		lang = w.Lang
+	}
+
+	if c.opts.CodeWrappers != WrapperSnippet || w.Ext == "" {
+		return fencedBlock(lang, body, prefix, indent)
+	}
+
+	name := fmt.Sprintf("%s-%s.%s", strings.ReplaceAll(c.fileTitle(c.page.Title), "/", "-"), child.UID, w.Ext)
+	rel := snippetDir + "/" + name
+	c.snippets[rel] = strings.Join(body, "\n") + "\n"
+
+	return []string{fmt.Sprintf("%s%s snippet: [[%s]]", prefix, lang, rel)}
+}
+
+// writeSnippets writes the snippet files collected in snippet mode.
+func (c *Converter) writeSnippets() error {
+	paths := make([]string, 0, len(c.snippets))
+	for p := range c.snippets {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if err := c.w.WriteFile(p, []byte(c.snippets[p])); err != nil {
+			return err
+		}
+		c.stats.Assets++
+	}
+
+	return nil
+}
+
+// fencedBlock renders a fenced code block. The first line starts with prefix
+// and the rest with indent so the block lines up with a list item.
+func fencedBlock(lang string, body []string, prefix, indent string) []string {
+	lines := []string{prefix + "```" + lang}
+	for _, line := range body {
+		lines = append(lines, indent+line)
+	}
+	lines = append(lines, indent+"```")
+
+	return lines
+}
+
+// splitFence removes a code fence wrapped around s and returns the code
+// and the fence's language tag.
+func splitFence(s string) (string, string) {
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") || len(trimmed) < 6 {
+		return s, ""
+	}
+
+	body := strings.TrimSuffix(trimmed, "```")
+	lang := ""
+	if i := strings.Index(body, "\n"); i >= 0 {
+		lang = strings.TrimSpace(body[3:i])
+		body = body[i+1:]
+	} else {
+		body = strings.TrimPrefix(body, "```")
+	}
+
+	return strings.TrimRight(body, "\n"), lang
+}
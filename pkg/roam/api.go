@@ -0,0 +1,235 @@
+package roam
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// APIURL is the Roam backend API.
+	APIURL = "https://api.roamresearch.com"
+
+	// apiBatch is how many pages a pull-many request pulls.
+	apiBatch = 100
+	// apiRetries is how often a rate limited request is retried.
+	apiRetries = 5
+	// apiRetryWait is how long a rate limited request waits before it is
+	// retried, when the response doesn't say.
+	apiRetryWait = 15 * time.Second
+)
+
+// apiSelector pulls a page or block with the attributes an export has, and
+// its children with the same selector.
+const apiSelector = `[:node/title :block/uid :block/string :block/order :block/heading :block/text-align
+ :children/view-type :block/props :create/time :edit/time
+ {:create/user [:user/uid]} {:edit/user [:user/uid]} {:block/refs [:block/uid]} {:block/children ...}]`
+
+// apiKeys maps the attributes pulled from the API to the keys of a JSON
+// export. Attributes not listed keep their name.
+var apiKeys = map[string]string{
+	":node/title":       "title",
+	":block/uid":        "uid",
+	":block/string":     "string",
+	":block/heading":    "heading",
+	":block/text-align": "text-align",
+	":block/children":   "children",
+	":create/time":      "create-time",
+	":edit/time":        "edit-time",
+}
+
+// APIClient reads a graph with the Roam backend API.
+type APIClient struct {
+	// Graph is the name of the graph.
+	Graph string
+	// Token is a graph API token, made in the graph's settings.
+	Token string
+	// URL is the API to use. Defaults to APIURL.
+	URL string
+	// HTTP makes the requests. Defaults to a client with a timeout.
+	HTTP *http.Client
+}
+
+// NewAPIClient creates a client for graph that authenticates with token.
+func NewAPIClient(graph, token string) *APIClient {
+	return &APIClient{
+		Graph: graph,
+		Token: token,
+		URL:   APIURL,
+		HTTP:  &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// Pages pulls every page of the graph, finding the pages with a query and
+// pulling them a batch at a time. The pages are sorted by title.
+func (c *APIClient) Pages() ([]Page, error) {
+	var uids [][]string
+	if err := c.call("q", map[string]interface{}{
+		"query": `[:find ?uid :where [?p :node/title] [?p :block/uid ?uid]]`,
+	}, &uids); err != nil {
+		return nil, fmt.Errorf("find pages: %w", err)
+	}
+
+	var raw []interface{}
+	for start := 0; start < len(uids); start += apiBatch {
+		end := start + apiBatch
+		if end > len(uids) {
+			end = len(uids)
+		}
+
+		eids := make([]string, 0, end-start)
+		for _, row := range uids[start:end] {
+			if len(row) > 0 {
+				eids = append(eids, "[:block/uid "+strconv.Quote(row[0])+"]")
+			}
+		}
+
+		var batch []interface{}
+		if err := c.call("pull-many", map[string]interface{}{
+			"eids":     "[" + strings.Join(eids, " ") + "]",
+			"selector": apiSelector,
+		}, &batch); err != nil {
+			return nil, fmt.Errorf("pull pages: %w", err)
+		}
+
+		for _, page := range batch {
+			if m, ok := page.(map[string]interface{}); ok && m[":node/title"] != nil {
+				raw = append(raw, exportShape(m))
+			}
+		}
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := Load(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(pages, func(i, j int) bool {
+		return pages[i].Title < pages[j].Title
+	})
+	linkPages(pages)
+
+	return pages, nil
+}
+
+// call posts body to the endpoint of the graph and decodes the result into
+// out. Rate limited requests are retried.
+func (c *APIClient) call(endpoint string, body interface{}, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	base := c.URL
+	if base == "" {
+		base = APIURL
+	}
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, base+"/api/graph/"+url.PathEscape(c.Graph)+"/"+endpoint, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+		// the API redirects to the graph's peer, which reads the token from
+		// this header
+		req.Header.Set("X-Authorization", "Bearer "+c.Token)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		payload, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < apiRetries {
+			wait := apiRetryWait
+			if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				wait = time.Duration(s) * time.Second
+			}
+			time.Sleep(wait)
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			var e struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(payload, &e) == nil && e.Message != "" {
+				return fmt.Errorf("%s: %s", resp.Status, e.Message)
+			}
+			return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(payload)))
+		}
+
+		var result struct {
+			Result json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal(payload, &result); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+
+		return json.Unmarshal(result.Result, out)
+	}
+}
+
+// exportShape rewrites a page or block pulled from the API to how a JSON
+// export writes it, with children in their order.
+func exportShape(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if name, ok := apiKeys[k]; ok {
+			k = name
+		}
+
+		switch k {
+		case "children":
+			children, _ := v.([]interface{})
+			sort.SliceStable(children, func(i, j int) bool {
+				return blockOrder(children[i]) < blockOrder(children[j])
+			})
+
+			shaped := make([]interface{}, 0, len(children))
+			for _, child := range children {
+				if cm, ok := child.(map[string]interface{}); ok {
+					shaped = append(shaped, exportShape(cm))
+				}
+			}
+			v = shaped
+		case "text-align":
+			if s, ok := v.(string); ok {
+				v = strings.TrimPrefix(s, ":")
+			}
+		}
+
+		out[k] = v
+	}
+
+	return out
+}
+
+func blockOrder(block interface{}) float64 {
+	m, _ := block.(map[string]interface{})
+	order, _ := m[":block/order"].(float64)
+	return order
+}
@@ -0,0 +1,149 @@
+package roam
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DailyPattern recognizes daily note titles that don't use Roam's own format,
+// such as "Journal: 3 April 2021".
+type DailyPattern struct {
+	// Pattern is a regular expression that must match the whole title.
+	Pattern string `json:"pattern"`
+	// Year, Month and Day name the capture groups holding each part of the
+	// date, by group name or number. They default to the groups named year,
+	// month and day. The month may be a number or a month name.
+	Year  string `json:"year,omitempty"`
+	Month string `json:"month,omitempty"`
+	Day   string `json:"day,omitempty"`
+	// Months are the month names, January first, for titles that aren't in
+	// English. They're matched ignoring case.
+	Months []string `json:"months,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// NewDailyPattern compiles a daily pattern that uses the named groups year,
+// month and day.
+func NewDailyPattern(pattern string) (*DailyPattern, error) {
+	p := &DailyPattern{Pattern: pattern}
+	if err := p.Compile(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Compile compiles the pattern and checks that its date groups exist.
+func (p *DailyPattern) Compile() error {
+	re, err := regexp.Compile(`^(?:` + p.Pattern + `)$`)
+	if err != nil {
+		return fmt.Errorf("daily pattern %q: %w", p.Pattern, err)
+	}
+	p.re = re
+
+	if len(p.Months) != 0 && len(p.Months) != 12 {
+		return fmt.Errorf("daily pattern %q has %d months, want 12", p.Pattern, len(p.Months))
+	}
+
+	for _, group := range []string{p.yearGroup(), p.monthGroup(), p.dayGroup()} {
+		if p.groupIndex(group) < 0 {
+			return fmt.Errorf("daily pattern %q has no group %q", p.Pattern, group)
+		}
+	}
+
+	return nil
+}
+
+// Match parses title. The bool result reports whether the title matched the
+// pattern; the error is set when it matched but isn't a valid date.
+func (p *DailyPattern) Match(title string) (time.Time, bool, error) {
+	m := p.re.FindStringSubmatch(strings.TrimSpace(title))
+	if m == nil {
+		return time.Time{}, false, nil
+	}
+
+	year, err := strconv.Atoi(m[p.groupIndex(p.yearGroup())])
+	if err != nil {
+		return time.Time{}, true, fmt.Errorf("year: %w", err)
+	}
+
+	rawMonth := m[p.groupIndex(p.monthGroup())]
+	month := p.monthByName(rawMonth)
+	if month == 0 {
+		n, err := strconv.Atoi(rawMonth)
+		if err != nil || n < 1 || n > 12 {
+			return time.Time{}, true, fmt.Errorf("unknown month %q", rawMonth)
+		}
+		month = time.Month(n)
+	}
+
+	day, err := strconv.Atoi(m[p.groupIndex(p.dayGroup())])
+	if err != nil {
+		return time.Time{}, true, fmt.Errorf("day: %w", err)
+	}
+
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if t.Day() != day || t.Month() != month {
+		return time.Time{}, true, fmt.Errorf("%s has no day %d", month, day)
+	}
+
+	return t, true, nil
+}
+
+// monthByName returns the month named name in the pattern's months or, when
+// it has none, in English.
+func (p *DailyPattern) monthByName(name string) time.Month {
+	if len(p.Months) == 0 {
+		if m := MonthByName(name); m != 0 {
+			return m
+		}
+		return monthByAbbreviation(name)
+	}
+
+	for i, m := range p.Months {
+		if strings.EqualFold(m, name) {
+			return time.Month(i + 1)
+		}
+	}
+
+	return 0
+}
+
+func (p *DailyPattern) groupIndex(group string) int {
+	if n, err := strconv.Atoi(group); err == nil {
+		if n > 0 && n <= p.re.NumSubexp() {
+			return n
+		}
+		return -1
+	}
+
+	return p.re.SubexpIndex(group)
+}
+
+func (p *DailyPattern) yearGroup() string  { return groupOrDefault(p.Year, "year") }
+func (p *DailyPattern) monthGroup() string { return groupOrDefault(p.Month, "month") }
+func (p *DailyPattern) dayGroup() string   { return groupOrDefault(p.Day, "day") }
+
+func groupOrDefault(group, def string) string {
+	if group == "" {
+		return def
+	}
+
+	return group
+}
+
+// monthByAbbreviation returns the month with the given three letter English
+// abbreviation, ignoring case.
+func monthByAbbreviation(name string) time.Month {
+	for m := time.January; m <= time.December; m++ {
+		if strings.EqualFold(m.String()[:3], name) {
+			return m
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,108 @@
+package roam
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDailyPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern DailyPattern
+		in      string
+		want    time.Time
+		matched bool
+		wantErr bool
+	}{
+		{
+			pattern: DailyPattern{Pattern: `Journal: (?P<day>\d+) (?P<month>\w+) (?P<year>\d{4})`},
+			in:      "Journal: 3 April 2021",
+			want:    day(2021, time.April, 3),
+			matched: true,
+		},
+		{
+			pattern: DailyPattern{Pattern: `Journal: (?P<day>\d+) (?P<month>\w+) (?P<year>\d{4})`},
+			in:      "Journal: 3 apr 2021",
+			want:    day(2021, time.April, 3),
+			matched: true,
+		},
+		{
+			pattern: DailyPattern{Pattern: `(\d{4})/(\d{2})/(\d{2})`, Year: "1", Month: "2", Day: "3"},
+			in:      "2021/04/03",
+			want:    day(2021, time.April, 3),
+			matched: true,
+		},
+		{
+			pattern: DailyPattern{Pattern: `(\d{4})/(\d{2})/(\d{2})`, Year: "1", Month: "2", Day: "3"},
+			in:      "2021/02/30",
+			matched: true,
+			wantErr: true,
+		},
+		{
+			pattern: DailyPattern{Pattern: `(\d{4})/(\d{2})/(\d{2})`, Year: "1", Month: "2", Day: "3"},
+			in:      "2021/13/01",
+			matched: true,
+			wantErr: true,
+		},
+		{
+			pattern: DailyPattern{Pattern: `(\d{4})/(\d{2})/(\d{2})`, Year: "1", Month: "2", Day: "3"},
+			in:      "Notes 2021/04/03",
+		},
+	}
+
+	for _, tt := range tests {
+		p := tt.pattern
+		if err := p.Compile(); err != nil {
+			t.Fatalf("Compile(%q): %v", p.Pattern, err)
+		}
+
+		got, matched, err := p.Match(tt.in)
+		if matched != tt.matched || (err != nil) != tt.wantErr {
+			t.Errorf("Match(%q) = %v, %v, want %v, error %v", tt.in, matched, err, tt.matched, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Match(%q) = %s, want %s", tt.in, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestDailyLocales(t *testing.T) {
+	tests := []struct {
+		locale string
+		in     string
+		want   time.Time
+	}{
+		{"de", "3. Januar 2022", day(2022, time.January, 3)},
+		{"es", "3 de enero de 2022", day(2022, time.January, 3)},
+		{"fr", "3 janvier 2022", day(2022, time.January, 3)},
+	}
+
+	for _, tt := range tests {
+		l, ok := LookupDateLocale(tt.locale)
+		if !ok {
+			t.Fatalf("no date locale %q", tt.locale)
+		}
+		p, err := l.DailyPattern()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.locale, err)
+		}
+
+		got, matched, err := p.Match(tt.in)
+		if !matched || err != nil || !got.Equal(tt.want) {
+			t.Errorf("%s: Match(%q) = %s, %v, %v", tt.locale, tt.in, got.Format("2006-01-02"), matched, err)
+		}
+	}
+}
+
+func TestDailyPatternCompile(t *testing.T) {
+	for _, p := range []DailyPattern{
+		{Pattern: `(`},
+		{Pattern: `(?P<year>\d{4})-(?P<month>\d{2})`},
+		{Pattern: `(?P<year>\d{4})-(?P<month>\d{2})-(?P<day>\d{2})`, Months: []string{"a"}},
+		{Pattern: `(\d{4})`, Year: "1", Month: "2", Day: "3"},
+	} {
+		if err := p.Compile(); err == nil {
+			t.Errorf("Compile(%q) succeeded, want an error", p.Pattern)
+		}
+	}
+}
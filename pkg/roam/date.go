@@ -0,0 +1,71 @@
+package roam
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DatePattern is a regular expression matching the date part of a Roam
+// daily note title. It has groups for the month name, day and year.
+const DatePattern = `(?i:(January|February|March|April|May|June|July|August|September|October|November|December))\s+([0-9]{1,2})(?i:st|nd|rd|th)?,?\s+([0-9]{4})`
+
+// ParseDate parses a Roam daily note title such as "January 3rd, 2022".
+// Parsing is lenient: month names are case-insensitive, and the ordinal
+// suffix and comma are optional and not checked against the day. The bool
+// result reports whether in looks like a daily note title at all.
+func ParseDate(in string) (time.Time, bool, error) {
+	match := reDaily.FindStringSubmatch(strings.TrimSpace(in))
+	if match == nil {
+		return time.Time{}, false, nil
+	}
+
+	month := MonthByName(match[1])
+	day, _ := strconv.Atoi(match[2])
+	year, _ := strconv.Atoi(match[3])
+
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if t.Day() != day || t.Month() != month {
+		return time.Time{}, true, fmt.Errorf("%s has no day %d", month, day)
+	}
+
+	return t, true, nil
+}
+
+// MonthByName returns the month with the given English name, ignoring case.
+func MonthByName(name string) time.Month {
+	for m := time.January; m <= time.December; m++ {
+		if strings.EqualFold(m.String(), name) {
+			return m
+		}
+	}
+
+	return 0
+}
+
+// FormatDate formats t the way Roam titles daily notes.
+func FormatDate(t time.Time) string {
+	return fmt.Sprintf("%s %d%s, %d", t.Month(), t.Day(), OrdinalSuffix(t.Day()), t.Year())
+}
+
+// OrdinalSuffix returns the English ordinal suffix for n.
+func OrdinalSuffix(n int) string {
+	if n%100 >= 11 && n%100 <= 13 {
+		return "th"
+	}
+
+	switch n % 10 {
+	case 1:
+		return "st"
+	case 2:
+		return "nd"
+	case 3:
+		return "rd"
+	default:
+		return "th"
+	}
+}
+
+var reDaily = regexp.MustCompile(`^` + DatePattern + `$`)
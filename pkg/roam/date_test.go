@@ -0,0 +1,151 @@
+package roam
+
+import (
+	"testing"
+	"time"
+)
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestOrdinalSuffix(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{1, "st"},
+		{2, "nd"},
+		{3, "rd"},
+		{4, "th"},
+		{11, "th"},
+		{12, "th"},
+		{13, "th"},
+		{21, "st"},
+		{22, "nd"},
+		{23, "rd"},
+		{31, "st"},
+		{101, "st"},
+		{111, "th"},
+	}
+
+	for _, tt := range tests {
+		if got := OrdinalSuffix(tt.n); got != tt.want {
+			t.Errorf("OrdinalSuffix(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	tests := []struct {
+		in   time.Time
+		want string
+	}{
+		{day(2022, time.January, 1), "January 1st, 2022"},
+		{day(2022, time.January, 2), "January 2nd, 2022"},
+		{day(2022, time.January, 3), "January 3rd, 2022"},
+		{day(2022, time.January, 4), "January 4th, 2022"},
+		{day(2022, time.March, 11), "March 11th, 2022"},
+		{day(2022, time.March, 12), "March 12th, 2022"},
+		{day(2022, time.March, 13), "March 13th, 2022"},
+		{day(2022, time.May, 21), "May 21st, 2022"},
+		{day(2022, time.May, 22), "May 22nd, 2022"},
+		{day(2022, time.May, 23), "May 23rd, 2022"},
+		{day(2022, time.December, 31), "December 31st, 2022"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatDate(tt.in); got != tt.want {
+			t.Errorf("FormatDate(%s) = %q, want %q", tt.in.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Time
+		daily   bool
+		wantErr bool
+	}{
+		{in: "January 1st, 2022", want: day(2022, time.January, 1), daily: true},
+		{in: "January 2nd, 2022", want: day(2022, time.January, 2), daily: true},
+		{in: "January 3rd, 2022", want: day(2022, time.January, 3), daily: true},
+		{in: "January 4th, 2022", want: day(2022, time.January, 4), daily: true},
+		{in: "March 11th, 2022", want: day(2022, time.March, 11), daily: true},
+		{in: "March 12th, 2022", want: day(2022, time.March, 12), daily: true},
+		{in: "March 13th, 2022", want: day(2022, time.March, 13), daily: true},
+		{in: "May 21st, 2022", want: day(2022, time.May, 21), daily: true},
+		{in: "May 22nd, 2022", want: day(2022, time.May, 22), daily: true},
+		{in: "May 23rd, 2022", want: day(2022, time.May, 23), daily: true},
+		{in: "December 31st, 2022", want: day(2022, time.December, 31), daily: true},
+
+		// lenient input
+		{in: "January 3, 2022", want: day(2022, time.January, 3), daily: true},
+		{in: "January 3 2022", want: day(2022, time.January, 3), daily: true},
+		{in: "january 3RD, 2022", want: day(2022, time.January, 3), daily: true},
+		{in: "JANUARY 3rd, 2022", want: day(2022, time.January, 3), daily: true},
+		{in: "January 3th, 2022", want: day(2022, time.January, 3), daily: true},
+		{in: "January   3rd,   2022", want: day(2022, time.January, 3), daily: true},
+		{in: "  January 3rd, 2022  ", want: day(2022, time.January, 3), daily: true},
+		{in: "January 03rd, 2022", want: day(2022, time.January, 3), daily: true},
+
+		// daily titles that aren't dates
+		{in: "February 30th, 2022", daily: true, wantErr: true},
+		{in: "February 29th, 2023", daily: true, wantErr: true},
+		{in: "February 29th, 2024", want: day(2024, time.February, 29), daily: true},
+
+		// not daily titles
+		{in: "January 3rd"},
+		{in: "Jan 3rd, 2022"},
+		{in: "Notes on January 3rd, 2022"},
+		{in: "2022-01-03"},
+		{in: ""},
+	}
+
+	for _, tt := range tests {
+		got, daily, err := ParseDate(tt.in)
+		if daily != tt.daily {
+			t.Errorf("ParseDate(%q) daily = %v, want %v", tt.in, daily, tt.daily)
+			continue
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDate(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %s, want %s", tt.in, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestDateRoundTrip(t *testing.T) {
+	for d := day(2020, time.January, 1); d.Year() == 2020; d = d.AddDate(0, 0, 1) {
+		title := FormatDate(d)
+		got, daily, err := ParseDate(title)
+		if err != nil || !daily || !got.Equal(d) {
+			t.Fatalf("ParseDate(FormatDate(%s)) = %s, %v, %v", d.Format("2006-01-02"), got.Format("2006-01-02"), daily, err)
+		}
+		if again := FormatDate(got); again != title {
+			t.Fatalf("FormatDate(ParseDate(%q)) = %q", title, again)
+		}
+	}
+
+	// lenient titles format to the canonical one
+	for in, want := range map[string]string{
+		"january 3, 2022":     "January 3rd, 2022",
+		"MAY 22ND 2022":       "May 22nd, 2022",
+		"March  11st,  2022":  "March 11th, 2022",
+		"december 31 2022":    "December 31st, 2022",
+		"  June 2nd, 2021   ": "June 2nd, 2021",
+	} {
+		got, _, err := ParseDate(in)
+		if err != nil {
+			t.Errorf("ParseDate(%q): %v", in, err)
+			continue
+		}
+		if title := FormatDate(got); title != want {
+			t.Errorf("FormatDate(ParseDate(%q)) = %q, want %q", in, title, want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package roam
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The EDN reader only understands the subset of EDN found in Roam exports:
+// vectors, lists, maps, sets, strings, keywords, symbols, numbers, booleans,
+// nil and tagged literals (whose tag is dropped). Sets are read as vectors
+// and map keys are converted to strings.
+
+type (
+	// EDNVector is a vector or set.
+	EDNVector []interface{}
+	// EDNList is a list.
+	EDNList []interface{}
+	// EDNMap is a map keyed by the string form of its keys.
+	EDNMap map[string]interface{}
+	// EDNKeyword is a keyword without its leading colon.
+	EDNKeyword string
+	// EDNSymbol is a symbol.
+	EDNSymbol string
+)
+
+// ParseEDN reads a single EDN form from src. Strings decode to string,
+// numbers to float64 and booleans to bool.
+func ParseEDN(src string) (interface{}, error) {
+	p := &ednParser{src: src}
+	v, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.skipSpace(); p.pos < len(p.src) {
+		return nil, errors.New("trailing data after EDN form")
+	}
+
+	return v, nil
+}
+
+type ednParser struct {
+	src string
+	pos int
+}
+
+func (p *ednParser) parse() (interface{}, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, errors.New("unexpected end of input")
+	}
+
+	switch ch := p.src[p.pos]; {
+	case ch == '[':
+		p.pos++
+		items, err := p.parseSeq(']')
+		return EDNVector(items), err
+	case ch == '(':
+		p.pos++
+		items, err := p.parseSeq(')')
+		return EDNList(items), err
+	case ch == '{':
+		p.pos++
+		return p.parseMap()
+	case ch == '#':
+		p.pos++
+		if p.pos < len(p.src) && p.src[p.pos] == '{' {
+			p.pos++
+			items, err := p.parseSeq('}')
+			return EDNVector(items), err
+		}
+		// tagged literal such as #uuid "..."; keep the value
+		p.parseToken()
+		return p.parse()
+	case ch == '"':
+		return p.parseString()
+	case ch == ':':
+		p.pos++
+		return EDNKeyword(p.parseToken()), nil
+	case ch == ']' || ch == ')' || ch == '}':
+		return nil, fmt.Errorf("unexpected %q at offset %d", ch, p.pos)
+	default:
+		tok := p.parseToken()
+		switch tok {
+		case "nil":
+			return nil, nil
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		if f, err := strconv.ParseFloat(tok, 64); err == nil {
+			return f, nil
+		}
+		return EDNSymbol(tok), nil
+	}
+}
+
+func (p *ednParser) parseSeq(end byte) ([]interface{}, error) {
+	var items []interface{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("missing %q", end)
+		}
+		if p.src[p.pos] == end {
+			p.pos++
+			return items, nil
+		}
+
+		v, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+}
+
+func (p *ednParser) parseMap() (interface{}, error) {
+	items, err := p.parseSeq('}')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items)%2 != 0 {
+		return nil, errors.New("map has an odd number of forms")
+	}
+
+	m := EDNMap{}
+	for i := 0; i < len(items); i += 2 {
+		m[EDNString(items[i])] = items[i+1]
+	}
+
+	return m, nil
+}
+
+func (p *ednParser) parseString() (interface{}, error) {
+	p.pos++
+
+	var sb strings.Builder
+	for p.pos < len(p.src) {
+		ch := p.src[p.pos]
+		p.pos++
+
+		switch ch {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			if p.pos >= len(p.src) {
+				return nil, errors.New("unterminated string")
+			}
+			esc := p.src[p.pos]
+			p.pos++
+			switch esc {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(esc)
+			}
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+
+	return nil, errors.New("unterminated string")
+}
+
+func (p *ednParser) parseToken() string {
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(" \t\r\n,[](){}\"", rune(p.src[p.pos])) {
+		p.pos++
+	}
+
+	return p.src[start:p.pos]
+}
+
+func (p *ednParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ' ', '\t', '\r', '\n', ',':
+			p.pos++
+		case ';':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// EDNString renders a scalar EDN value as plain text. Vectors are joined
+// with spaces.
+func EDNString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case EDNKeyword:
+		return string(v)
+	case EDNSymbol:
+		return string(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case EDNVector:
+		parts := make([]string, len(v))
+		for i := range v {
+			parts[i] = EDNString(v[i])
+		}
+		return strings.Join(parts, " ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,139 @@
+package roam
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Importer reads the export of an outliner into pages. The Roam JSON
+// importer is built in; other outliners can be supported by registering an
+// Importer with Register.
+type Importer interface {
+	// Name identifies the import format, as used by the -format flag.
+	Name() string
+	// Detect reports whether the file at p looks like this format.
+	Detect(p string) bool
+	// Import reads the file at p.
+	Import(p string) ([]Page, error)
+}
+
+// ImporterRegistry holds the importers that can read input files.
+type ImporterRegistry struct {
+	importers []Importer
+}
+
+// NewImporterRegistry creates a registry holding importers.
+func NewImporterRegistry(importers ...Importer) *ImporterRegistry {
+	r := &ImporterRegistry{}
+	for _, imp := range importers {
+		if err := r.Register(imp); err != nil {
+			panic(err)
+		}
+	}
+
+	return r
+}
+
+// Register adds imp to the registry. Names must be unique.
+func (r *ImporterRegistry) Register(imp Importer) error {
+	if _, ok := r.Lookup(imp.Name()); ok {
+		return fmt.Errorf("importer %q is already registered", imp.Name())
+	}
+
+	r.importers = append(r.importers, imp)
+
+	return nil
+}
+
+// Lookup returns the importer named name.
+func (r *ImporterRegistry) Lookup(name string) (Importer, bool) {
+	for _, imp := range r.importers {
+		if strings.EqualFold(imp.Name(), name) {
+			return imp, true
+		}
+	}
+
+	return nil, false
+}
+
+// Detect returns the importer for the file at p. Importers registered later
+// are asked first, so a registered importer can claim files the built-in
+// ones would also accept.
+func (r *ImporterRegistry) Detect(p string) (Importer, error) {
+	for i := len(r.importers) - 1; i >= 0; i-- {
+		if r.importers[i].Detect(p) {
+			return r.importers[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no importer recognizes %s (known formats: %s)", p, strings.Join(r.Names(), ", "))
+}
+
+// Names returns the names of the registered importers, sorted.
+func (r *ImporterRegistry) Names() []string {
+	names := make([]string, 0, len(r.importers))
+	for _, imp := range r.importers {
+		names = append(names, imp.Name())
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Import reads the file at p with the importer named format, or the one that
+// recognizes the file when format is empty.
+func (r *ImporterRegistry) Import(p, format string) ([]Page, error) {
+	var imp Importer
+	if format == "" {
+		var err error
+		if imp, err = r.Detect(p); err != nil {
+			return nil, err
+		}
+	} else {
+		var ok bool
+		if imp, ok = r.Lookup(format); !ok {
+			return nil, fmt.Errorf("unknown format %q (known formats: %s)", format, strings.Join(r.Names(), ", "))
+		}
+	}
+
+	pages, err := imp.Import(p)
+	if err != nil {
+		return nil, fmt.Errorf("%s import: %w", imp.Name(), err)
+	}
+
+	return pages, nil
+}
+
+// FormatRoam is the name of the Roam JSON importer.
+const FormatRoam = "roam"
+
+// roamImporter reads Roam JSON exports and the zip files they are
+// downloaded in.
+type roamImporter struct{}
+
+func (roamImporter) Name() string {
+	return FormatRoam
+}
+
+func (roamImporter) Detect(p string) bool {
+	if p == Stdin {
+		return true
+	}
+
+	ext := strings.ToLower(path.Ext(p))
+	return ext == ".json" || ext == ".zip"
+}
+
+func (roamImporter) Import(p string) ([]Page, error) {
+	return LoadFile(p)
+}
+
+// Importers is the registry used by the command line tool.
+var Importers = NewImporterRegistry(roamImporter{}, roamToGitImporter{}, logseqImporter{}, opmlImporter{})
+
+// Register adds imp to Importers.
+func Register(imp Importer) error {
+	return Importers.Register(imp)
+}
@@ -0,0 +1,112 @@
+package roam
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Load decodes a Roam JSON export.
+func Load(r io.Reader) ([]Page, error) {
+	var pages []Page
+
+	if err := json.NewDecoder(r).Decode(&pages); err != nil {
+		return nil, err
+	}
+
+	linkPages(pages)
+
+	return pages, nil
+}
+
+// linkPages sets the page of the top-level blocks of pages.
+func linkPages(pages []Page) {
+	for i := range pages {
+		for j := range pages[i].Children() {
+			pages[i].RawChildren[j].Page = &pages[i]
+		}
+	}
+}
+
+// Stdin is the input path that reads the export from standard input.
+const Stdin = "-"
+
+// LoadFile decodes the Roam JSON export at jsonPath, or standard input when
+// it is Stdin. Zip files, as downloaded from Roam, are read from the first
+// JSON file inside them.
+func LoadFile(jsonPath string) ([]Page, error) {
+	if jsonPath == Stdin {
+		return LoadStdin()
+	}
+
+	if strings.EqualFold(path.Ext(jsonPath), ".zip") {
+		return LoadZip(jsonPath)
+	}
+
+	f, err := os.Open(jsonPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f)
+}
+
+// LoadStdin decodes a Roam JSON export, or a zip file holding one, read from
+// standard input.
+func LoadStdin() ([]Page, error) {
+	br := bufio.NewReader(os.Stdin)
+
+	magic, _ := br.Peek(4)
+	if !bytes.Equal(magic, []byte("PK\x03\x04")) {
+		return Load(br)
+	}
+
+	// zip files are read from the end, so the archive is buffered
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	return loadZip(zr, "standard input")
+}
+
+// LoadZip decodes the first JSON file in the zip archive at zipPath.
+func LoadZip(zipPath string) ([]Page, error) {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	return loadZip(&zr.Reader, zipPath)
+}
+
+func loadZip(zr *zip.Reader, name string) ([]Page, error) {
+	for _, f := range zr.File {
+		if !strings.EqualFold(path.Ext(f.Name), ".json") {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		return Load(r)
+	}
+
+	return nil, fmt.Errorf("%s has no JSON file", name)
+}
@@ -0,0 +1,116 @@
+package roam
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DateLocale describes how daily note titles are written in a language
+// other than English, such as "3 de enero de 2022". Locales are turned into
+// daily patterns; more can be added with RegisterDateLocale.
+type DateLocale struct {
+	// Name identifies the locale, as used by the -daily-locale flag.
+	Name string
+	// Pattern matches the whole title. It has groups named day and year and
+	// a %s where the month name goes.
+	Pattern string
+	// Months are the month names, January first.
+	Months [12]string
+}
+
+// DailyPattern returns the daily pattern matching titles in the locale.
+func (l *DateLocale) DailyPattern() (*DailyPattern, error) {
+	names := make([]string, len(l.Months))
+	for i, m := range l.Months {
+		if m == "" {
+			return nil, fmt.Errorf("date locale %q has no name for month %d", l.Name, i+1)
+		}
+		names[i] = regexp.QuoteMeta(m)
+	}
+
+	p := &DailyPattern{
+		Pattern: fmt.Sprintf(l.Pattern, `(?P<month>(?i:`+strings.Join(names, "|")+`))`),
+		Months:  l.Months[:],
+	}
+	if err := p.Compile(); err != nil {
+		return nil, fmt.Errorf("date locale %q: %w", l.Name, err)
+	}
+
+	return p, nil
+}
+
+var dateLocales = map[string]*DateLocale{}
+
+// RegisterDateLocale adds l to the locales that can be looked up by name.
+// Names must be unique.
+func RegisterDateLocale(l *DateLocale) error {
+	name := strings.ToLower(l.Name)
+	if _, ok := dateLocales[name]; ok {
+		return fmt.Errorf("date locale %q is already registered", l.Name)
+	}
+
+	if _, err := l.DailyPattern(); err != nil {
+		return err
+	}
+	dateLocales[name] = l
+
+	return nil
+}
+
+// LookupDateLocale returns the locale named name, ignoring case.
+func LookupDateLocale(name string) (*DateLocale, bool) {
+	l, ok := dateLocales[strings.ToLower(name)]
+	return l, ok
+}
+
+// DateLocaleNames returns the names of the registered locales, sorted.
+func DateLocaleNames() []string {
+	names := make([]string, 0, len(dateLocales))
+	for _, l := range dateLocales {
+		names = append(names, l.Name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func init() {
+	for _, l := range []*DateLocale{
+		{
+			Name:    "de",
+			Pattern: `(?P<day>[0-9]{1,2})\.?\s+%s\s+(?P<year>[0-9]{4})`,
+			Months:  [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		},
+		{
+			Name:    "es",
+			Pattern: `(?P<day>[0-9]{1,2})\s+de\s+%s\s+(?i:de|del)\s+(?P<year>[0-9]{4})`,
+			Months:  [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		},
+		{
+			Name:    "fr",
+			Pattern: `(?P<day>[0-9]{1,2})(?:er)?\s+%s\s+(?P<year>[0-9]{4})`,
+			Months:  [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		},
+		{
+			Name:    "it",
+			Pattern: `(?P<day>[0-9]{1,2})[º°]?\s+%s\s+(?P<year>[0-9]{4})`,
+			Months:  [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		},
+		{
+			Name:    "nl",
+			Pattern: `(?P<day>[0-9]{1,2})\s+%s\s+(?P<year>[0-9]{4})`,
+			Months:  [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		},
+		{
+			Name:    "pt",
+			Pattern: `(?P<day>[0-9]{1,2})\s+de\s+%s\s+de\s+(?P<year>[0-9]{4})`,
+			Months:  [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		},
+	} {
+		if err := RegisterDateLocale(l); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package roam
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FormatLogseq is the name of the importer for Logseq graph exports.
+const FormatLogseq = "logseq"
+
+// logseqImporter reads the JSON and EDN graph exports of Logseq, whose
+// pages and blocks are mapped onto Roam's: UUIDs are replaced by Roam style
+// uids, task markers, headings and embeds are written the Roam way, and
+// journal pages are given Roam daily titles.
+type logseqImporter struct{}
+
+func (logseqImporter) Name() string {
+	return FormatLogseq
+}
+
+// Detect accepts EDN files, and JSON files holding an object rather than
+// the list of pages of a Roam export.
+func (logseqImporter) Detect(p string) bool {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".edn":
+		return true
+	case ".json":
+	default:
+		return false
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	for {
+		r, _, err := br.ReadRune()
+		if err != nil {
+			return false
+		}
+		if !unicode.IsSpace(r) && r != '\ufeff' {
+			return r == '{'
+		}
+	}
+}
+
+func (logseqImporter) Import(p string) ([]Page, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var graph interface{}
+	if strings.EqualFold(path.Ext(p), ".edn") {
+		graph, err = ParseEDN(string(data))
+	} else {
+		err = json.Unmarshal(data, &graph)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return LogseqPages(graph)
+}
+
+// LogseqPages maps a decoded Logseq export, a map with a blocks list of
+// pages, onto Roam pages.
+func LogseqPages(graph interface{}) ([]Page, error) {
+	m := logseqMap(graph)
+	if m == nil {
+		return nil, fmt.Errorf("logseq export is not a map")
+	}
+
+	ls := &logseqReader{uids: map[string]string{}, used: map[string]struct{}{}}
+	pages := logseqList(logseqGet(m, "blocks"))
+	for i, page := range pages {
+		if logseqMap(page) == nil {
+			return nil, fmt.Errorf("logseq page %d is not a map", i+1)
+		}
+		ls.assignUIDs(logseqList(logseqGet(logseqMap(page), "children")))
+	}
+
+	var out []Page
+	for _, raw := range pages {
+		page := logseqMap(raw)
+		title := ednText(logseqGet(page, "page-name"))
+		if title == "" {
+			continue
+		}
+		if t, ok := logseqJournalDate(title); ok {
+			title = FormatDate(t)
+		}
+
+		out = append(out, Page{
+			Title:       title,
+			RawChildren: ls.blocks(logseqList(logseqGet(page, "children"))),
+		})
+	}
+
+	linkPages(out)
+
+	return out, nil
+}
+
+// logseqReader holds the uids given to the blocks of a Logseq export.
+type logseqReader struct {
+	// uids maps block UUIDs to the uids they were given.
+	uids map[string]string
+	used map[string]struct{}
+	n    int
+}
+
+// assignUIDs gives each block a nine character uid, the length Roam uses,
+// taken from its UUID when it has one.
+func (ls *logseqReader) assignUIDs(blocks []interface{}) {
+	for _, raw := range blocks {
+		block := logseqMap(raw)
+		// blocks that aren't maps are left out
+		if block == nil {
+			continue
+		}
+		id := ednText(logseqGet(block, "id"))
+
+		uid := ""
+		if hex := strings.ReplaceAll(id, "-", ""); len(hex) >= 9 {
+			for i := 0; i+9 <= len(hex); i++ {
+				if _, ok := ls.used[hex[i:i+9]]; !ok {
+					uid = hex[i : i+9]
+					break
+				}
+			}
+		}
+		for uid == "" {
+			ls.n++
+			if _, ok := ls.used[fmt.Sprintf("lsq%06d", ls.n)]; !ok {
+				uid = fmt.Sprintf("lsq%06d", ls.n)
+			}
+		}
+
+		ls.used[uid] = struct{}{}
+		if id != "" {
+			ls.uids[id] = uid
+		}
+		block["uid"] = uid
+
+		ls.assignUIDs(logseqList(logseqGet(block, "children")))
+	}
+}
+
+func (ls *logseqReader) blocks(blocks []interface{}) []Child {
+	var children []Child
+	for _, raw := range blocks {
+		block := logseqMap(raw)
+		if block == nil {
+			continue
+		}
+
+		s, heading := logseqContent(ednText(logseqGet(block, "content")))
+		if n, ok := logseqGet(block, "heading-level").(float64); ok && heading == 0 {
+			heading = int(n)
+		}
+		if heading > 3 {
+			heading = 3
+		}
+
+		children = append(children, Child{
+			UID:         ednText(block["uid"]),
+			String:      ls.blockRefs(s),
+			Heading:     heading,
+			RawChildren: ls.blocks(logseqList(logseqGet(block, "children"))),
+		})
+	}
+
+	return children
+}
+
+// blockRefs replaces the UUIDs in block refs with the uids of the blocks.
+func (ls *logseqReader) blockRefs(s string) string {
+	return reLogseqRef.ReplaceAllStringFunc(s, func(ref string) string {
+		if uid, ok := ls.uids[ref[2:len(ref)-2]]; ok {
+			return "((" + uid + "))"
+		}
+		return ref
+	})
+}
+
+// logseqContent rewrites the text of a Logseq block the way Roam writes it,
+// returning the heading level taken from a leading #.
+func logseqContent(s string) (string, int) {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if !reLogseqHiddenProp.MatchString(line) {
+			lines = append(lines, line)
+		}
+	}
+	s = strings.Join(lines, "\n")
+
+	heading := 0
+	if m := reLogseqHeading.FindStringSubmatch(s); m != nil && len(m[1]) <= 3 {
+		heading = len(m[1])
+		s = s[len(m[0]):]
+	}
+
+	if m := reLogseqMarker.FindStringSubmatch(s); m != nil {
+		mark := "TODO"
+		if m[1] == "DONE" {
+			mark = "DONE"
+		}
+		s = "{{[[" + mark + "]]}} " + s[len(m[0]):]
+	}
+
+	s = reLogseqEmbed.ReplaceAllString(s, "{{embed: ")
+	s = reWikiTarget.ReplaceAllStringFunc(s, func(link string) string {
+		if t, ok := logseqJournalDate(link[2 : len(link)-2]); ok {
+			return "[[" + FormatDate(t) + "]]"
+		}
+		return link
+	})
+
+	return s, heading
+}
+
+// logseqJournalDate parses the title of a Logseq journal page, in the
+// default "Jan 3rd, 2022" format or as yyyy-MM-dd or yyyy_MM_dd.
+func logseqJournalDate(title string) (time.Time, bool) {
+	if m := reLogseqJournal.FindStringSubmatch(title); m != nil {
+		month := monthByAbbreviation(m[1])
+		day, _ := strconv.Atoi(m[2])
+		year, _ := strconv.Atoi(m[3])
+		t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return t, month != 0 && t.Day() == day
+	}
+
+	if m := reLogseqISODate.FindStringSubmatch(title); m != nil {
+		t, err := time.Parse("2006-01-02", m[1]+"-"+m[2]+"-"+m[3])
+		return t, err == nil
+	}
+
+	return time.Time{}, false
+}
+
+func logseqGet(m map[string]interface{}, key string) interface{} {
+	if v, ok := m[key]; ok {
+		return v
+	}
+
+	return m["block/"+key]
+}
+
+func logseqMap(v interface{}) map[string]interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return v
+	case EDNMap:
+		return v
+	}
+
+	return nil
+}
+
+func logseqList(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		return v
+	case EDNVector:
+		return v
+	case EDNList:
+		return v
+	}
+
+	return nil
+}
+
+// ednText returns a string or keyword value as text.
+func ednText(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+
+	return EDNString(v)
+}
+
+var (
+	reLogseqHiddenProp = regexp.MustCompile(`^\s*(?:id|collapsed|heading)::`)
+	reLogseqHeading    = regexp.MustCompile(`^(#{1,6}) `)
+	reLogseqMarker     = regexp.MustCompile(`^(TODO|DOING|LATER|NOW|WAIT|WAITING|IN-PROGRESS|DONE) `)
+	reLogseqEmbed      = regexp.MustCompile(`\{\{embed `)
+	reLogseqRef        = regexp.MustCompile(`\(\([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\)\)`)
+	reLogseqJournal    = regexp.MustCompile(`^(?i:([a-z]{3}))\s+(\d{1,2})(?:st|nd|rd|th)?,\s+(\d{4})$`)
+	reLogseqISODate    = regexp.MustCompile(`^(\d{4})[-_](\d{2})[-_](\d{2})$`)
+	reWikiTarget       = regexp.MustCompile(`\[\[[^\[\]\n]+\]\]`)
+)
@@ -0,0 +1,145 @@
+package roam
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FormatOPML is the name of the importer for OPML outlines.
+const FormatOPML = "opml"
+
+// opmlImporter reads OPML outlines, as exported by Workflowy and Dynalist.
+// Every top-level outline is a page and the outlines below it are its
+// blocks.
+type opmlImporter struct{}
+
+func (opmlImporter) Name() string {
+	return FormatOPML
+}
+
+func (opmlImporter) Detect(p string) bool {
+	return strings.EqualFold(path.Ext(p), ".opml")
+}
+
+func (opmlImporter) Import(p string) ([]Page, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var doc struct {
+		Body struct {
+			Outlines []opmlOutline `xml:"outline"`
+		} `xml:"body"`
+	}
+	if err := xml.NewDecoder(f).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse OPML: %w", err)
+	}
+
+	op := &opmlReader{}
+	var pages []Page
+	index := map[string]int{}
+	for _, o := range doc.Body.Outlines {
+		title := strings.TrimSpace(stripOPMLMarkup(o.Text))
+		if title == "" {
+			continue
+		}
+
+		children := op.blocks(o.Outlines)
+		if o.Note != "" {
+			children = append([]Child{{UID: op.uid(), String: opmlNote(o.Note)}}, children...)
+		}
+
+		// outlines with the same title are merged into one page
+		if i, ok := index[title]; ok {
+			pages[i].RawChildren = append(pages[i].RawChildren, children...)
+			continue
+		}
+		index[title] = len(pages)
+		pages = append(pages, Page{Title: title, RawChildren: children})
+	}
+
+	linkPages(pages)
+
+	return pages, nil
+}
+
+// opmlOutline is an outline element. Workflowy and Dynalist keep notes in
+// _note and mark completed items with _complete or complete.
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Note     string        `xml:"_note,attr"`
+	Complete string        `xml:"_complete,attr"`
+	Checked  string        `xml:"complete,attr"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// opmlReader numbers the blocks of an outline.
+type opmlReader struct {
+	n int64
+}
+
+// uid returns the next nine character uid, the length Roam uses.
+func (op *opmlReader) uid() string {
+	op.n++
+	s := strconv.FormatInt(op.n, 36)
+	return "op" + strings.Repeat("0", 7-len(s)) + s
+}
+
+func (op *opmlReader) blocks(outlines []opmlOutline) []Child {
+	var children []Child
+	for _, o := range outlines {
+		s := opmlMarkdown(o.Text)
+		if o.Complete == "true" || o.Checked == "true" {
+			s = "{{[[DONE]]}} " + s
+		}
+		if o.Note != "" {
+			s += "\n" + opmlNote(o.Note)
+		}
+
+		children = append(children, Child{
+			UID:         op.uid(),
+			String:      s,
+			RawChildren: op.blocks(o.Outlines),
+		})
+	}
+
+	return children
+}
+
+// opmlMarkdown writes the HTML formatting Workflowy and Dynalist allow in
+// outline text as Markdown.
+func opmlMarkdown(s string) string {
+	s = reOPMLLink.ReplaceAllString(s, "[$2]($1)")
+	s = reOPMLBold.ReplaceAllString(s, "**$2**")
+	s = reOPMLItalic.ReplaceAllString(s, "*$2*")
+	s = reOPMLStrike.ReplaceAllString(s, "~~$2~~")
+	s = reOPMLCode.ReplaceAllString(s, "`$1`")
+
+	return stripOPMLMarkup(s)
+}
+
+func opmlNote(note string) string {
+	return strings.TrimSpace(opmlMarkdown(note))
+}
+
+// stripOPMLMarkup removes the tags left in s and decodes its entities.
+func stripOPMLMarkup(s string) string {
+	return html.UnescapeString(reOPMLTag.ReplaceAllString(s, ""))
+}
+
+var (
+	reOPMLLink   = regexp.MustCompile(`(?is)<a\s[^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
+	reOPMLBold   = regexp.MustCompile(`(?is)<(b|strong)>(.*?)</(?:b|strong)>`)
+	reOPMLItalic = regexp.MustCompile(`(?is)<(i|em)>(.*?)</(?:i|em)>`)
+	reOPMLStrike = regexp.MustCompile(`(?is)<(s|strike|del)>(.*?)</(?:s|strike|del)>`)
+	reOPMLCode   = regexp.MustCompile(`(?is)<code>(.*?)</code>`)
+	reOPMLTag    = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+)
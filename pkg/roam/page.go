@@ -0,0 +1,256 @@
+// Package roam reads Roam Research JSON exports.
+package roam
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Parent is a page or block that has child blocks.
+type Parent interface {
+	Children() []Child
+}
+
+// Page is a Roam page.
+type Page struct {
+	Title         string   `json:"title"`
+	RawChildren   []Child  `json:"children"`
+	RawCreateTime int      `json:"create-time"`
+	CreateEmail   string   `json:"create-email"`
+	RawEditTime   int      `json:"edit-time"`
+	EditEmail     string   `json:"edit-email"`
+	CreateUser    *UserRef `json:":create/user,omitempty"`
+	EditUser      *UserRef `json:":edit/user,omitempty"`
+
+	CreateTime time.Time `json:"-"`
+	EditTime   time.Time `json:"-"`
+
+	IsDaily bool `json:"-"`
+	// FileSuffix is added to the page's filename by a conversion, to tell
+	// it apart from another page with the same title.
+	FileSuffix string `json:"-"`
+}
+
+func (p *Page) Children() []Child {
+	return p.RawChildren
+}
+
+// Creator identifies the user who created the page: their email or, in
+// exports that only name users by uid, their uid.
+func (p *Page) Creator() string {
+	return creator(p.CreateEmail, p.CreateUser)
+}
+
+var _ json.Unmarshaler = &Page{}
+var _ Parent = &Page{}
+
+type dummyPage Page
+
+func (p *Page) UnmarshalJSON(bytes []byte) error {
+	d := &struct {
+		*dummyPage
+		CreateEmail userField `json:"create-email"`
+		EditEmail   userField `json:"edit-email"`
+	}{dummyPage: &dummyPage{}}
+
+	if err := json.Unmarshal(bytes, d); err != nil {
+		return err
+	}
+
+	p.Title = d.Title
+	p.RawChildren = d.RawChildren
+	p.CreateEmail = d.CreateEmail.Email
+	p.EditEmail = d.EditEmail.Email
+	p.CreateUser = d.CreateEmail.or(d.CreateUser)
+	p.EditUser = d.EditEmail.or(d.EditUser)
+
+	p.RawCreateTime = d.RawCreateTime
+	p.RawEditTime = d.RawEditTime
+	p.CreateTime = epochTime(d.RawCreateTime)
+	p.EditTime = epochTime(d.RawEditTime)
+
+	return nil
+}
+
+// Block view types.
+const (
+	ViewBullet   = "bullet"
+	ViewNumbered = "numbered"
+	ViewDocument = "document"
+)
+
+// Child is a Roam block.
+type Child struct {
+	UID           string  `json:"uid"`
+	String        string  `json:"string"`
+	RawChildren   []Child `json:"children"`
+	RawCreateTime int     `json:"create-time"`
+	CreateEmail   string  `json:"create-email"`
+	RawEditTime   int     `json:"edit-time"`
+	EditEmail     string  `json:"edit-email"`
+	Heading       int     `json:"heading"`
+	Emojis        []Emoji `json:"emojis"`
+	TextAlign     string  `json:"text-align"`
+	// ViewType is how the block's children are shown: ViewBullet,
+	// ViewNumbered or ViewDocument.
+	ViewType string `json:"view-type,omitempty"`
+	// RawViewType is the view type as newer exports write it, a keyword
+	// such as ":numbered".
+	RawViewType string `json:":children/view-type,omitempty"`
+	// Refs are the pages and blocks the block references, when the export
+	// lists them. Nil means the export doesn't.
+	Refs       []Ref    `json:":block/refs,omitempty"`
+	CreateUser *UserRef `json:":create/user,omitempty"`
+	EditUser   *UserRef `json:":edit/user,omitempty"`
+	// Starred is set on blocks that were starred or pinned in Roam.
+	Starred bool `json:"starred,omitempty"`
+	// Props are the block's properties, such as the lines of a drawing.
+	// RawProps are the properties as newer exports write them.
+	Props    json.RawMessage `json:"props,omitempty"`
+	RawProps json.RawMessage `json:":block/props,omitempty"`
+
+	CreateTime time.Time `json:"-"`
+	EditTime   time.Time `json:"-"`
+
+	// Page is the page the block is on. Loading sets it on top-level
+	// blocks.
+	Page *Page `json:"-"`
+}
+
+var _ json.Unmarshaler = &Child{}
+var _ Parent = &Child{}
+
+type dummyChild Child
+
+func (c *Child) Children() []Child {
+	return c.RawChildren
+}
+
+// Creator identifies the user who created the block, like Page.Creator.
+func (c *Child) Creator() string {
+	return creator(c.CreateEmail, c.CreateUser)
+}
+
+func (c *Child) UnmarshalJSON(bytes []byte) error {
+	d := &struct {
+		*dummyChild
+		CreateEmail userField `json:"create-email"`
+		EditEmail   userField `json:"edit-email"`
+	}{dummyChild: &dummyChild{}}
+
+	if err := json.Unmarshal(bytes, d); err != nil {
+		return err
+	}
+	c.UID = d.UID
+	c.String = d.String
+	c.RawChildren = d.RawChildren
+	c.CreateEmail = d.CreateEmail.Email
+	c.EditEmail = d.EditEmail.Email
+	c.Heading = d.Heading
+	c.Emojis = d.Emojis
+	c.TextAlign = d.TextAlign
+	c.ViewType = d.ViewType
+	if c.ViewType == "" {
+		c.ViewType = strings.TrimPrefix(d.RawViewType, ":")
+	}
+	c.RawViewType = d.RawViewType
+	c.Refs = d.Refs
+	c.CreateUser = d.CreateEmail.or(d.CreateUser)
+	c.EditUser = d.EditEmail.or(d.EditUser)
+	c.Starred = d.Starred
+	c.Props = d.Props
+	if len(c.Props) == 0 {
+		c.Props = d.RawProps
+	}
+	c.RawProps = d.RawProps
+
+	c.RawCreateTime = d.RawCreateTime
+	c.RawEditTime = d.RawEditTime
+	c.CreateTime = epochTime(d.RawCreateTime)
+	c.EditTime = epochTime(d.RawEditTime)
+
+	return nil
+}
+
+// Ref is a page or block referenced by a block.
+type Ref struct {
+	UID string `json:":block/uid"`
+}
+
+// UserRef identifies the Roam user who created or edited a page or block.
+type UserRef struct {
+	UID string `json:":user/uid,omitempty"`
+}
+
+func creator(email string, user *UserRef) string {
+	if email == "" && user != nil {
+		return user.UID
+	}
+
+	return email
+}
+
+// userField is a create-email or edit-email field, which newer exports write
+// as a user map rather than an email.
+type userField struct {
+	Email string
+	User  *UserRef
+}
+
+func (f *userField) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		return json.Unmarshal(data, &f.User)
+	}
+
+	return json.Unmarshal(data, &f.Email)
+}
+
+// or returns the user in the field, or user when it holds an email.
+func (f userField) or(user *UserRef) *UserRef {
+	if user == nil {
+		return f.User
+	}
+
+	return user
+}
+
+// Emoji is an emoji reaction on a block.
+type Emoji struct {
+	Emoji map[string]interface{}   `json:"emoji"`
+	Users []map[string]interface{} `json:"users"`
+}
+
+// Native returns the emoji character, or its name in colons when the export
+// doesn't include the character.
+func (e Emoji) Native() string {
+	if s, ok := e.Emoji["native"].(string); ok && s != "" {
+		return s
+	}
+
+	if skins, ok := e.Emoji["skins"].([]interface{}); ok && len(skins) > 0 {
+		if skin, ok := skins[0].(map[string]interface{}); ok {
+			if s, ok := skin["native"].(string); ok && s != "" {
+				return s
+			}
+		}
+	}
+
+	for _, key := range []string{"id", "name"} {
+		if s, ok := e.Emoji[key].(string); ok && s != "" {
+			return ":" + s + ":"
+		}
+	}
+
+	return ""
+}
+
+// epochTime converts a Roam timestamp, in milliseconds since the epoch. A
+// missing timestamp is the zero time.
+func epochTime(ms int) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, int64(ms)*int64(time.Millisecond))
+}
@@ -0,0 +1,127 @@
+package roam
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FormatRoamToGit is the name of the importer for roam-to-git backups.
+const FormatRoamToGit = "roam-to-git"
+
+// roamToGitImporter reads roam-to-git backup repositories, which keep the
+// graph in JSON files in a json directory. It takes the repository's
+// directory or a git URL, which is cloned.
+type roamToGitImporter struct{}
+
+func (roamToGitImporter) Name() string {
+	return FormatRoamToGit
+}
+
+func (roamToGitImporter) Detect(p string) bool {
+	if isGitURL(p) {
+		return true
+	}
+
+	files, err := roamToGitFiles(p)
+	return err == nil && len(files) > 0
+}
+
+func (roamToGitImporter) Import(p string) ([]Page, error) {
+	if isGitURL(p) {
+		dir, err := os.MkdirTemp("", "goroam2obs-git-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		out, err := exec.Command("git", "clone", "--quiet", "--depth", "1", p, dir).CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("git clone %s: %w: %s", p, err, strings.TrimSpace(string(out)))
+		}
+		p = dir
+	}
+
+	files, err := roamToGitFiles(p)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no JSON files in %s", p)
+	}
+
+	var sets [][]Page
+	for _, file := range files {
+		pages, err := LoadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		sets = append(sets, pages)
+	}
+
+	return MergePages(sets...), nil
+}
+
+// roamToGitFiles returns the JSON files of the backup in dir: those in its
+// json directory, or in dir itself when it is that directory.
+func roamToGitFiles(dir string) ([]string, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	if fi, err := os.Stat(filepath.Join(dir, "json")); err == nil && fi.IsDir() {
+		dir = filepath.Join(dir, "json")
+	}
+
+	return filepath.Glob(filepath.Join(dir, "*.json"))
+}
+
+// isGitURL reports whether p names a remote git repository rather than a
+// file.
+func isGitURL(p string) bool {
+	ext := strings.ToLower(path.Ext(p))
+	if ext == ".json" || ext == ".zip" {
+		return false
+	}
+
+	for _, prefix := range []string{"https://", "http://", "ssh://", "git://", "file://", "git@"} {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MergePages merges page sets into one. A page in more than one set is
+// taken from the set where it was edited last, or the first such set.
+func MergePages(sets ...[]Page) []Page {
+	var merged []Page
+	index := map[string]int{}
+
+	for _, pages := range sets {
+		for _, page := range pages {
+			i, ok := index[page.Title]
+			if !ok {
+				index[page.Title] = len(merged)
+				merged = append(merged, page)
+				continue
+			}
+
+			if page.RawEditTime > merged[i].RawEditTime {
+				merged[i] = page
+			}
+		}
+	}
+
+	linkPages(merged)
+
+	return merged
+}
@@ -0,0 +1,75 @@
+package vault
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// Encrypted is a Writer that age-encrypts every file before passing it on.
+// Encrypted files get an .age extension.
+type Encrypted struct {
+	w          Writer
+	recipients []age.Recipient
+}
+
+var _ Writer = &Encrypted{}
+var _ Remover = &Encrypted{}
+
+// NewEncrypted creates an Encrypted writer that encrypts to recipients and
+// writes to w.
+func NewEncrypted(w Writer, recipients ...age.Recipient) *Encrypted {
+	return &Encrypted{w: w, recipients: recipients}
+}
+
+// WriteFile encrypts data and writes it to name with .age appended.
+func (e *Encrypted) WriteFile(name string, data []byte) error {
+	var buf bytes.Buffer
+	enc, err := age.Encrypt(&buf, e.recipients...)
+	if err != nil {
+		return fmt.Errorf("encrypt %s: %w", name, err)
+	}
+
+	if _, err := enc.Write(data); err != nil {
+		return fmt.Errorf("encrypt %s: %w", name, err)
+	}
+
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("encrypt %s: %w", name, err)
+	}
+
+	return e.w.WriteFile(name+".age", buf.Bytes())
+}
+
+// Remove removes the encrypted file for name, when the wrapped Writer can
+// remove files.
+func (e *Encrypted) Remove(name string) error {
+	rm, ok := e.w.(Remover)
+	if !ok {
+		return fmt.Errorf("remove %s: not supported", name)
+	}
+
+	return rm.Remove(name + ".age")
+}
+
+// ParseRecipient parses an -encrypt spec of the form age:<recipient>, where
+// the recipient is an age X25519 public key.
+func ParseRecipient(spec string) (age.Recipient, error) {
+	scheme, key := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		scheme, key = spec[:i], spec[i+1:]
+	}
+
+	if scheme != "age" {
+		return nil, fmt.Errorf("unknown encryption %q, expected age:<recipient>", scheme)
+	}
+
+	r, err := age.ParseX25519Recipient(key)
+	if err != nil {
+		return nil, fmt.Errorf("age recipient: %w", err)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,495 @@
+package vault
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// generatedNotes are the notes a conversion writes at the root of the vault
+// that aren't Roam pages.
+var generatedNotes = map[string]struct{}{
+	"Conversion Report.md":      {},
+	"Needs Manual Migration.md": {},
+}
+
+// exportBlock is a block read from a note, with its nesting level.
+type exportBlock struct {
+	child *roam.Child
+	level int
+}
+
+// Export reads the notes of a vault written by a conversion and rebuilds the
+// Roam pages they came from. Pages are titled by the title in their
+// frontmatter, or else by their filename. Blocks keep the UIDs of their
+// ^anchors and get stable generated UIDs otherwise, aliases in frontmatter
+// become an Alias:: block, links to daily notes use Roam dates again, and
+// links to an anchor that follow the text of the block they point at become
+// block references. Quarantined pages are restored from the JSON kept in
+// their notes.
+func Export(root string) ([]roam.Page, error) {
+	var pages []roam.Page
+	// titles maps the notes whose frontmatter holds the page title, as
+	// -slug writes it, to that title
+	titles := map[string]string{}
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			switch d.Name() {
+			case ".obsidian", ".trash", "snippets", "drawings":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.EqualFold(path.Ext(rel), ".md") || isGeneratedNote(rel) {
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(rel, "_quarantine/") {
+			page, ok, err := quarantinedPage(data)
+			if err != nil {
+				return fmt.Errorf("read quarantined page %s: %w", rel, err)
+			}
+			if ok {
+				pages = append(pages, page)
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		page := parsePage(rel, string(data), info.ModTime())
+		if name := pageTitle(rel); page.Title != name {
+			titles[name] = page.Title
+		}
+		pages = append(pages, page)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].Title < pages[j].Title
+	})
+
+	restoreTitleLinks(pages, titles)
+	restoreBlockRefs(pages)
+
+	return pages, nil
+}
+
+func isGeneratedNote(rel string) bool {
+	if _, ok := generatedNotes[rel]; ok {
+		return true
+	}
+
+	// folder index notes
+	base := path.Base(rel)
+	return path.Dir(rel) != "." && (base == "README.md" || base == "_about.md")
+}
+
+// quarantinedPage decodes the Roam JSON kept in a quarantined note.
+func quarantinedPage(data []byte) (roam.Page, bool, error) {
+	m := reQuarantineJSON.FindSubmatch(data)
+	if m == nil {
+		return roam.Page{}, false, nil
+	}
+
+	var page roam.Page
+	if err := json.Unmarshal(m[1], &page); err != nil {
+		return roam.Page{}, false, err
+	}
+
+	return page, true, nil
+}
+
+// pageTitle turns the path of a note back into a page title.
+func pageTitle(rel string) string {
+	title := strings.TrimSuffix(rel, path.Ext(rel))
+	if day, ok := dailyTitle(title); ok {
+		return day
+	}
+
+	return title
+}
+
+// dailyTitle returns the Roam title for the daily note target, which may be
+// in the daily folder or not.
+func dailyTitle(target string) (string, bool) {
+	name := strings.TrimPrefix(target, "daily/")
+	if t, err := time.Parse("2006-01-02", name); err == nil {
+		return roam.FormatDate(t), true
+	}
+	if name != target {
+		if _, ok, err := roam.ParseDate(name); ok && err == nil {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// parsePage reads the blocks of a note. Every line is a block, nested by its
+// indentation, except that fenced code stays in the block that opens it.
+func parsePage(rel, text string, modTime time.Time) roam.Page {
+	ms := int(modTime.UnixNano() / int64(time.Millisecond))
+	page := roam.Page{
+		Title:         pageTitle(rel),
+		RawCreateTime: ms,
+		RawEditTime:   ms,
+	}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	lines, title, aliases := splitFrontmatter(lines)
+	if title != "" {
+		page.Title = title
+		// a slugged note has its title as an alias too
+		aliases = removeString(aliases, title)
+	}
+
+	var top []*roam.Child
+	children := map[*roam.Child][]*roam.Child{}
+	var stack []exportBlock
+
+	if len(aliases) > 0 {
+		links := make([]string, len(aliases))
+		for i, alias := range aliases {
+			links[i] = "[[" + alias + "]]"
+		}
+		top = append(top, &roam.Child{
+			UID:           generatedUID(rel, -1),
+			String:        "Alias:: " + strings.Join(links, ", "),
+			RawCreateTime: ms,
+			RawEditTime:   ms,
+		})
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.TrimSpace(line) == "## Backlinks" {
+			break
+		}
+
+		level, marked := indentLevel(line)
+		body, numbered := stripMarker(marked)
+
+		if strings.HasPrefix(body, "```") {
+			// the rest of the fence lines up with the text after the marker
+			indent := line[:len(line)-len(body)]
+			fence := []string{body}
+			for i+1 < len(lines) {
+				i++
+				next := lines[i]
+				if strings.HasPrefix(next, indent) {
+					next = next[len(indent):]
+				} else {
+					next = strings.TrimLeft(next, " \t")
+				}
+				fence = append(fence, next)
+				if strings.HasPrefix(strings.TrimSpace(next), "```") {
+					break
+				}
+			}
+			body = strings.Join(fence, "\n")
+		}
+
+		child := &roam.Child{RawCreateTime: ms, RawEditTime: ms}
+		if m := reTrailingAnchor.FindStringSubmatchIndex(body); m != nil {
+			child.UID = body[m[2]:m[3]]
+			body = body[:m[0]]
+		} else {
+			child.UID = generatedUID(rel, i)
+		}
+		if m := reHeadingPrefix.FindStringSubmatch(body); m != nil {
+			child.Heading = len(m[1])
+			body = body[len(m[0]):]
+		}
+		child.String = reDailyLink.ReplaceAllStringFunc(body, restoreDailyLink)
+
+		for len(stack) > 0 && stack[len(stack)-1].level >= level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			top = append(top, child)
+		} else {
+			parent := stack[len(stack)-1].child
+			children[parent] = append(children[parent], child)
+			if numbered {
+				parent.ViewType = roam.ViewNumbered
+			}
+		}
+		stack = append(stack, exportBlock{child: child, level: level})
+	}
+
+	var build func(c *roam.Child) roam.Child
+	build = func(c *roam.Child) roam.Child {
+		out := *c
+		out.RawChildren = nil
+		for _, child := range children[c] {
+			out.RawChildren = append(out.RawChildren, build(child))
+		}
+		return out
+	}
+
+	for _, child := range top {
+		page.RawChildren = append(page.RawChildren, build(child))
+	}
+
+	return page
+}
+
+// splitFrontmatter removes YAML frontmatter from lines and returns the
+// title and aliases listed in it.
+func splitFrontmatter(lines []string) ([]string, string, []string) {
+	if len(lines) == 0 || lines[0] != "---" {
+		return lines, "", nil
+	}
+
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if lines[i] == "---" {
+			end = i
+			break
+		}
+		// a horizontal rule at the top of a note rather than frontmatter
+		if !strings.HasPrefix(lines[i], " ") && !reFrontmatterKey.MatchString(lines[i]) {
+			return lines, "", nil
+		}
+	}
+	if end < 0 {
+		return lines, "", nil
+	}
+
+	var title string
+	var aliases []string
+	inAliases := false
+	for _, line := range lines[1:end] {
+		if !strings.HasPrefix(line, " ") {
+			inAliases = strings.TrimSpace(line) == "aliases:"
+			if value := strings.TrimPrefix(line, "title:"); value != line {
+				title = frontmatterValue(value)
+			}
+			continue
+		}
+		if !inAliases {
+			continue
+		}
+
+		if item := frontmatterValue(strings.TrimPrefix(strings.TrimSpace(line), "-")); item != "" {
+			aliases = append(aliases, item)
+		}
+	}
+
+	return lines[end+1:], title, aliases
+}
+
+// frontmatterValue returns a YAML scalar without its quotes.
+func frontmatterValue(s string) string {
+	s = strings.TrimSpace(s)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+
+	return s
+}
+
+func removeString(list []string, s string) []string {
+	var out []string
+	for _, item := range list {
+		if item != s {
+			out = append(out, item)
+		}
+	}
+
+	return out
+}
+
+// restoreTitleLinks points links to notes named other than their page, such
+// as slugged notes, back at the page title. Link text that is just the
+// title is dropped.
+func restoreTitleLinks(pages []roam.Page, titles map[string]string) {
+	if len(titles) == 0 {
+		return
+	}
+
+	restore := func(link string) string {
+		m := reDailyLink.FindStringSubmatch(link)
+		title, ok := titles[m[2]]
+		if !ok {
+			return link
+		}
+
+		rest := m[3]
+		if i := strings.LastIndex(rest, "|"); i >= 0 && rest[i+1:] == title {
+			rest = rest[:i]
+		}
+
+		return m[1] + "[[" + title + rest + "]]"
+	}
+
+	var walk func(children []roam.Child)
+	walk = func(children []roam.Child) {
+		for i := range children {
+			children[i].String = reDailyLink.ReplaceAllStringFunc(children[i].String, restore)
+			walk(children[i].RawChildren)
+		}
+	}
+	for i := range pages {
+		walk(pages[i].RawChildren)
+	}
+}
+
+// indentLevel returns how deeply line is nested, counting a tab or four
+// spaces as a level, and the line without its indentation.
+func indentLevel(line string) (int, string) {
+	level, spaces := 0, 0
+	for i, r := range line {
+		switch r {
+		case '\t':
+			level++
+			spaces = 0
+		case ' ':
+			spaces++
+			if spaces == 4 {
+				level++
+				spaces = 0
+			}
+		default:
+			return level, line[i:]
+		}
+	}
+
+	return level, ""
+}
+
+// stripMarker removes a list marker and reports whether it was numbered.
+func stripMarker(s string) (string, bool) {
+	if m := reListMarker.FindStringSubmatch(s); m != nil {
+		return s[len(m[0]):], m[1] != ""
+	}
+
+	return s, false
+}
+
+// generatedUID returns a stable Roam style UID for the block on line i of
+// the note at rel.
+func generatedUID(rel string, i int) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s:%d", rel, i)))
+	return base64.RawURLEncoding.EncodeToString(sum[:])[:9]
+}
+
+// restoreDailyLink turns a link to a daily note back into a Roam date link.
+func restoreDailyLink(link string) string {
+	m := reDailyLink.FindStringSubmatch(link)
+	target, rest := m[2], m[3]
+	if day, ok := dailyTitle(target); ok {
+		return m[1] + "[[" + day + rest + "]]"
+	}
+
+	return link
+}
+
+// restoreBlockRefs turns links to block anchors back into block references
+// and embeds. A conversion writes a reference as the text of the block
+// followed by a link to it, so that text is removed again.
+func restoreBlockRefs(pages []roam.Page) {
+	text := map[string]string{}
+	var collect func(children []roam.Child)
+	collect = func(children []roam.Child) {
+		for _, child := range children {
+			text[child.UID] = child.String
+			collect(child.RawChildren)
+		}
+	}
+	for _, page := range pages {
+		collect(page.RawChildren)
+	}
+
+	var restore func(children []roam.Child)
+	restore = func(children []roam.Child) {
+		for i := range children {
+			children[i].String = restoreRefs(children[i].String, text)
+			restore(children[i].RawChildren)
+		}
+	}
+	for i := range pages {
+		restore(pages[i].RawChildren)
+	}
+}
+
+func restoreRefs(s string, text map[string]string) string {
+	var sb strings.Builder
+	last := 0
+
+	for _, m := range reAnchorLink.FindAllStringSubmatchIndex(s, -1) {
+		uid := s[m[4]:m[5]]
+		ref, ok := text[uid]
+		if !ok {
+			continue
+		}
+
+		if m[3] > m[2] {
+			sb.WriteString(s[last:m[0]])
+			sb.WriteString("{{embed: ((" + uid + "))}}")
+			last = m[1]
+			continue
+		}
+
+		head := s[last:m[0]]
+		if ref != "" && strings.HasSuffix(head, ref+" ") {
+			head = strings.TrimSuffix(head, ref+" ")
+		}
+		sb.WriteString(head)
+		sb.WriteString("((" + uid + "))")
+		last = m[1]
+	}
+	sb.WriteString(s[last:])
+
+	return sb.String()
+}
+
+var (
+	reFrontmatterKey = regexp.MustCompile(`^[A-Za-z_][\w-]*:`)
+	reQuarantineJSON = regexp.MustCompile("(?s)```json\n(.*?)\n```")
+	reTrailingAnchor = regexp.MustCompile(`\s\^([A-Za-z0-9_-]+)\s*$`)
+	reHeadingPrefix  = regexp.MustCompile(`^(#{1,3})\s+`)
+	reListMarker     = regexp.MustCompile(`^(?:[-*+]|(\d+)\.)\s+`)
+	reDailyLink      = regexp.MustCompile(`(!?)\[\[([^\[\]\n#|]+)([^\[\]\n]*)\]\]`)
+	reAnchorLink     = regexp.MustCompile(`(!?)\[\[[^\[\]\n#|]*#\^([A-Za-z0-9_-]+)(?:\|[^\[\]\n]*)?\]\]`)
+)
@@ -0,0 +1,262 @@
+package vault
+
+import (
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Problem is a link in a note that doesn't resolve.
+type Problem struct {
+	// File is the note, relative to the vault root, and Line the line the
+	// link is on.
+	File string `json:"file"`
+	Line int    `json:"line"`
+	// Link is the link as written.
+	Link string `json:"link"`
+	// Kind is ProblemNote, ProblemAnchor, ProblemHeading or ProblemAsset.
+	Kind string `json:"kind"`
+}
+
+// Kinds of problems.
+const (
+	ProblemNote    = "missing note"
+	ProblemAnchor  = "missing block anchor"
+	ProblemHeading = "missing heading"
+	ProblemAsset   = "missing asset"
+)
+
+// ValidationReport lists the problems found in a vault.
+type ValidationReport struct {
+	Notes    int       `json:"notes"`
+	Links    int       `json:"links"`
+	Problems []Problem `json:"problems"`
+}
+
+// note is a Markdown note read during validation.
+type note struct {
+	rel      string
+	text     string
+	anchors  map[string]struct{}
+	headings map[string]struct{}
+}
+
+// Validate checks every wikilink, embed and local Markdown link in the notes
+// of the vault at root: the note or asset has to exist, and so do the block
+// anchors and headings they point at. Obsidian resolves wikilinks by path or
+// by file name, ignoring case, and so does Validate.
+func Validate(root string) (*ValidationReport, error) {
+	notes := map[string]*note{}
+	byName := map[string][]string{}
+	files := map[string]struct{}{}
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".obsidian" || d.Name() == ".trash" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		files[strings.ToLower(rel)] = struct{}{}
+		byName[strings.ToLower(path.Base(rel))] = append(byName[strings.ToLower(path.Base(rel))], rel)
+
+		if !strings.EqualFold(path.Ext(rel), ".md") {
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		notes[strings.ToLower(strings.TrimSuffix(rel, path.Ext(rel)))] = parseNote(rel, string(data))
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ValidationReport{Notes: len(notes)}
+
+	resolveNote := func(from, target string) *note {
+		key := strings.ToLower(strings.TrimSuffix(target, ".md"))
+		if n, ok := notes[key]; ok {
+			return n
+		}
+		if n, ok := notes[strings.ToLower(path.Join(path.Dir(from), key))]; ok {
+			return n
+		}
+		for _, rel := range byName[path.Base(key)+".md"] {
+			if strings.HasSuffix(strings.ToLower(strings.TrimSuffix(rel, ".md")), key) {
+				return notes[strings.ToLower(strings.TrimSuffix(rel, ".md"))]
+			}
+		}
+		return nil
+	}
+
+	resolveAsset := func(from, target string) bool {
+		key := strings.ToLower(target)
+		if _, ok := files[key]; ok {
+			return true
+		}
+		if _, ok := files[strings.ToLower(path.Join(path.Dir(from), target))]; ok {
+			return true
+		}
+		return len(byName[path.Base(key)]) > 0
+	}
+
+	keys := make([]string, 0, len(notes))
+	for key := range notes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		n := notes[key]
+		masked := maskCode(n.text)
+
+		add := func(offset int, link, kind string) {
+			r.Problems = append(r.Problems, Problem{
+				File: n.rel,
+				Line: strings.Count(n.text[:offset], "\n") + 1,
+				Link: link,
+				Kind: kind,
+			})
+		}
+
+		for _, m := range reWikiLink.FindAllStringSubmatchIndex(masked, -1) {
+			r.Links++
+			link := n.text[m[0]:m[1]]
+			target := n.text[m[2]:m[3]]
+			if i := strings.Index(target, "|"); i >= 0 {
+				target = target[:i]
+			}
+
+			anchor := ""
+			if i := strings.Index(target, "#"); i >= 0 {
+				target, anchor = target[:i], target[i+1:]
+			}
+
+			if target == "" {
+				if !n.hasAnchor(anchor) {
+					add(m[0], link, anchorKind(anchor))
+				}
+				continue
+			}
+
+			// titles such as "v1.2 notes" look like file names too
+			if ext := path.Ext(target); ext != "" && !strings.EqualFold(ext, ".md") && resolveNote(n.rel, target) == nil {
+				if !resolveAsset(n.rel, target) {
+					add(m[0], link, ProblemAsset)
+				}
+				continue
+			}
+
+			to := resolveNote(n.rel, target)
+			switch {
+			case to == nil:
+				add(m[0], link, ProblemNote)
+			case anchor != "" && !to.hasAnchor(anchor):
+				add(m[0], link, anchorKind(anchor))
+			}
+		}
+
+		for _, m := range reMarkdownLink.FindAllStringSubmatchIndex(masked, -1) {
+			dest := n.text[m[2]:m[3]]
+			if strings.Contains(dest, ":") || strings.HasPrefix(dest, "#") || strings.HasPrefix(dest, "/") {
+				continue
+			}
+			r.Links++
+
+			if i := strings.Index(dest, "#"); i >= 0 {
+				dest = dest[:i]
+			}
+			unescaped, err := url.PathUnescape(dest)
+			if err != nil {
+				unescaped = dest
+			}
+
+			if _, ok := files[strings.ToLower(path.Join(path.Dir(n.rel), unescaped))]; !ok {
+				kind := ProblemAsset
+				if strings.EqualFold(path.Ext(unescaped), ".md") {
+					kind = ProblemNote
+				}
+				add(m[0], n.text[m[0]:m[1]], kind)
+			}
+		}
+	}
+
+	return r, nil
+}
+
+func anchorKind(anchor string) string {
+	if strings.HasPrefix(anchor, "^") {
+		return ProblemAnchor
+	}
+	return ProblemHeading
+}
+
+func parseNote(rel, text string) *note {
+	n := &note{rel: rel, text: text, anchors: map[string]struct{}{}, headings: map[string]struct{}{}}
+
+	masked := maskCode(text)
+	for _, m := range reBlockAnchor.FindAllStringSubmatch(masked, -1) {
+		n.anchors[m[1]] = struct{}{}
+	}
+	for _, m := range reHeading.FindAllStringSubmatch(masked, -1) {
+		n.headings[strings.ToLower(strings.TrimSpace(m[1]))] = struct{}{}
+	}
+
+	return n
+}
+
+func (n *note) hasAnchor(anchor string) bool {
+	if strings.HasPrefix(anchor, "^") {
+		_, ok := n.anchors[anchor[1:]]
+		return ok
+	}
+
+	// nested headings are linked as a#b
+	if i := strings.LastIndex(anchor, "#"); i >= 0 {
+		anchor = anchor[i+1:]
+	}
+	_, ok := n.headings[strings.ToLower(strings.TrimSpace(anchor))]
+
+	return ok
+}
+
+// maskCode blanks out code spans and blocks, keeping offsets and line breaks,
+// so links inside code aren't checked.
+func maskCode(s string) string {
+	return reCode.ReplaceAllStringFunc(s, func(code string) string {
+		b := []byte(code)
+		for i := range b {
+			if b[i] != '\n' {
+				b[i] = ' '
+			}
+		}
+		return string(b)
+	})
+}
+
+var (
+	reWikiLink     = regexp.MustCompile(`!?\[\[([^\[\]\n]+)\]\]`)
+	reMarkdownLink = regexp.MustCompile(`!?\[[^\]\n]*\]\(([^)\s]+)\)`)
+	reBlockAnchor  = regexp.MustCompile(`(?m)\s\^([A-Za-z0-9_-]+)\s*$`)
+	reHeading      = regexp.MustCompile(`(?m)^#{1,6}\s+(.+?)\s*$`)
+	reCode         = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+)
@@ -0,0 +1,125 @@
+// Package vault writes converted notes into an Obsidian vault and checks the
+// links in a vault.
+package vault
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Writer stores the files of a vault. Names are slash separated and relative
+// to the root of the vault.
+type Writer interface {
+	WriteFile(name string, data []byte) error
+}
+
+// Reader is implemented by Writers that can read back files already in the
+// vault, so settings can be merged instead of replaced.
+type Reader interface {
+	ReadFile(name string) ([]byte, error)
+}
+
+// Remover is implemented by Writers that can remove a file from the vault.
+type Remover interface {
+	Remove(name string) error
+}
+
+// Discard is a Writer that drops every file.
+var Discard Writer = discard{}
+
+type discard struct{}
+
+func (discard) WriteFile(string, []byte) error { return nil }
+
+// Dir writes a vault into a directory.
+type Dir struct {
+	root string
+}
+
+var _ Writer = &Dir{}
+var _ Reader = &Dir{}
+var _ Remover = &Dir{}
+
+// NewDir creates a Dir rooted at root.
+func NewDir(root string) *Dir {
+	return &Dir{root: root}
+}
+
+// Root returns the directory the vault is written to.
+func (d *Dir) Root() string {
+	return d.root
+}
+
+// WriteFile writes data to name, creating parent directories as needed.
+func (d *Dir) WriteFile(name string, data []byte) error {
+	dest := filepath.Join(d.root, filepath.FromSlash(name))
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(dest, data, 0644)
+}
+
+// ReadFile reads name from the vault.
+func (d *Dir) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(d.root, filepath.FromSlash(name)))
+}
+
+// Remove removes name from the vault.
+func (d *Dir) Remove(name string) error {
+	return os.Remove(filepath.Join(d.root, filepath.FromSlash(name)))
+}
+
+// Memory keeps the files of a vault in memory.
+type Memory struct {
+	files map[string][]byte
+	order []string
+}
+
+var _ Writer = &Memory{}
+var _ Reader = &Memory{}
+var _ Remover = &Memory{}
+
+// NewMemory creates an empty Memory.
+func NewMemory() *Memory {
+	return &Memory{files: map[string][]byte{}}
+}
+
+// WriteFile stores a copy of data as name, replacing an earlier file.
+func (m *Memory) WriteFile(name string, data []byte) error {
+	if _, ok := m.files[name]; !ok {
+		m.order = append(m.order, name)
+	}
+	m.files[name] = append([]byte(nil), data...)
+
+	return nil
+}
+
+// ReadFile reads name from the files written so far.
+func (m *Memory) ReadFile(name string) ([]byte, error) {
+	data, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return data, nil
+}
+
+// Remove removes name from the files written so far.
+func (m *Memory) Remove(name string) error {
+	if _, ok := m.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+
+	delete(m.files, name)
+	for i, n := range m.order {
+		if n == name {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package vault
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Zip writes a vault into a zip archive. Files are kept in memory until
+// Close, so a file written twice, such as merged settings, is only stored
+// once.
+type Zip struct {
+	*Memory
+	path string
+}
+
+var _ Writer = &Zip{}
+var _ Reader = &Zip{}
+
+// NewZip creates a Zip that writes the archive at path.
+func NewZip(path string) *Zip {
+	return &Zip{Memory: NewMemory(), path: path}
+}
+
+// Close writes the archive, in the order the files were first written.
+func (z *Zip) Close() error {
+	if err := os.MkdirAll(filepath.Dir(z.path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(z.path)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(f)
+	now := time.Now()
+	for _, name := range z.order {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("zip %s: %w", name, err)
+		}
+		if _, err := w.Write(z.files[name]); err != nil {
+			f.Close()
+			return fmt.Errorf("zip %s: %w", name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bryanl/goram2obs/pkg/convert"
+	"github.com/bryanl/goram2obs/pkg/vault"
+)
+
+// previewCommand converts the pages given with -page and prints their notes
+// to stdout instead of writing a vault. It takes the conversion flags, so it
+// is handled by main rather than being one of the commands.
+const previewCommand = "preview"
+
+// preview prints the converted notes of the pages selected with -page. Each
+// note follows a header naming it when there is more than one.
+func preview(ac appConfig, opts convert.Options) error {
+	if len(opts.Pages) == 0 {
+		return errors.New("preview needs -page")
+	}
+
+	pages, err := ac.loadPages(ac.input)
+	if err != nil {
+		return fmt.Errorf("load input: %w", err)
+	}
+
+	opts.Progress = newProgressBar(progressModeNone, os.Stderr)
+	c, err := convert.New(opts)
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	mem := vault.NewMemory()
+	if err := c.Convert(pages, mem); err != nil {
+		return err
+	}
+
+	notes := c.Notes()
+	for i, note := range notes {
+		data, err := mem.ReadFile(note)
+		if err != nil {
+			return err
+		}
+
+		if len(notes) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("==> %s <==\n", note)
+		}
+		os.Stdout.Write(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			fmt.Println()
+		}
+	}
+
+	return nil
+}
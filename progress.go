@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cheggaaa/pb/v3"
+
+	"github.com/bryanl/goram2obs/pkg/convert"
+)
+
+const (
+	progressModeBar  = "bar"
+	progressModeJSON = "json"
+	progressModeNone = "none"
+)
+
+// progressBar shows the progress of each conversion pass on stderr and
+// records how long each pass took. In JSON mode it writes a line of JSON per
+// step instead, and in none mode it only records the timings.
+type progressBar struct {
+	mode    string
+	out     io.Writer
+	bar     *pb.ProgressBar
+	phase   string
+	done    int
+	total   int
+	started time.Time
+	phases  map[string]time.Duration
+}
+
+var _ convert.Progress = &progressBar{}
+
+// progressEvent is a line written in JSON mode.
+type progressEvent struct {
+	Phase string `json:"phase"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+}
+
+func newProgressBar(mode string, out io.Writer) *progressBar {
+	return &progressBar{mode: mode, out: out}
+}
+
+func (p *progressBar) Start(phase string, total int) {
+	p.phase = phase
+	p.done = 0
+	p.total = total
+	p.started = time.Now()
+
+	switch p.mode {
+	case progressModeBar:
+		p.bar = pb.New(total).SetWriter(p.out).Start()
+	case progressModeJSON:
+		p.event()
+	}
+}
+
+func (p *progressBar) Increment() {
+	p.done++
+
+	switch p.mode {
+	case progressModeBar:
+		p.bar.Increment()
+	case progressModeJSON:
+		p.event()
+	}
+}
+
+func (p *progressBar) Finish() {
+	switch p.mode {
+	case progressModeBar:
+		p.bar.Finish()
+	case progressModeJSON:
+		if p.done != p.total {
+			p.done = p.total
+			p.event()
+		}
+	}
+
+	if p.phases == nil {
+		p.phases = map[string]time.Duration{}
+	}
+	p.phases[p.phase] += time.Since(p.started)
+}
+
+func (p *progressBar) event() {
+	data, err := json.Marshal(progressEvent{Phase: p.phase, Done: p.done, Total: p.total})
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(p.out, "%s\n", data)
+}
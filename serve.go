@@ -0,0 +1,280 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bryanl/goram2obs/pkg/convert"
+)
+
+const (
+	// serveExportField is the form field holding the uploaded export.
+	serveExportField = "export"
+	// serveFlagsField holds extra conversion flags, written as on the command
+	// line.
+	serveFlagsField = "flags"
+)
+
+// serveFileFlags are the flags naming a file, which are uploaded in a form
+// field named after the flag rather than given as a path on the server.
+var serveFileFlags = []string{"config", "annotations", "user-map", "rules"}
+
+// serveDeniedFlags are the conversion flags a request can't set, since they
+// name files or directories on the server, read another input or change how
+// it logs.
+var serveDeniedFlags = map[string]struct{}{
+	"i": {}, "graph": {}, "token": {}, "d": {}, "o": {}, "watch": {}, "watch-interval": {}, "stats": {},
+	"history-file": {}, "no-history": {}, "resume": {}, "dry-run": {},
+	"v": {}, "vv": {}, "quiet": {}, "progress": {}, "log-format": {}, "log-page": {},
+}
+
+// runServe serves a form and an endpoint that convert an uploaded export
+// and send back the vault as a zip file.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "Address to listen on")
+	maxUpload := fs.Int64("max-upload", 512, "Largest export accepted, in MB")
+	jobs := fs.Int("jobs", 2, "How many conversions run at once")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *jobs < 1 {
+		return errors.New("-jobs must be at least 1")
+	}
+
+	s := &server{
+		lg:        newLogger(os.Stderr, levelInfo, false, nil),
+		maxUpload: *maxUpload << 20,
+		jobs:      make(chan struct{}, *jobs),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.form)
+	mux.HandleFunc("/convert", s.convert)
+
+	s.lg.Info("serving", "addr", *addr)
+
+	return http.ListenAndServe(*addr, mux)
+}
+
+// server converts uploaded exports.
+type server struct {
+	lg        *logger
+	maxUpload int64
+	// jobs holds a token for every conversion running.
+	jobs chan struct{}
+}
+
+var serveForm = template.Must(template.New("form").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Roam to Obsidian</title></head>
+<body>
+<h1>Convert a Roam export</h1>
+<form method="post" action="/convert" enctype="multipart/form-data">
+<p><label>Roam JSON export (.json or .zip) <input type="file" name="export" accept=".json,.zip" required></label></p>
+<p><label>Target <select name="target">{{range .Targets}}<option>{{.}}</option>{{end}}</select></label></p>
+<p><label>Block layout <select name="style">{{range .Styles}}<option>{{.}}</option>{{end}}</select></label></p>
+<p><label>Other flags <input type="text" name="flags" size="60" placeholder="-skip-empty -day-style roam"></label></p>
+<p><label>Config file (optional) <input type="file" name="config" accept=".json"></label></p>
+<p><button type="submit">Convert</button></p>
+</form>
+</body>
+</html>
+`))
+
+// form serves the upload form.
+func (s *server) form(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	err := serveForm.Execute(w, map[string][]string{
+		"Targets": {convert.TargetObsidian, convert.TargetLogseq, convert.TargetHTML},
+		"Styles":  {convert.StyleIndent, convert.StyleOutline, convert.StyleProse},
+	})
+	if err != nil {
+		s.lg.Error("write form", "err", err.Error())
+	}
+}
+
+// convert converts the export uploaded in the export field with the
+// conversion flags given in the other fields, each named after a flag, and
+// in the flags field. The vault is sent back as a zip file.
+func (s *server) convert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "convert needs a POST with a multipart form", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUpload)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, fmt.Sprintf("read upload: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	dir, err := os.MkdirTemp("", "goroam2obs-serve-")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	ac, err := s.config(r.MultipartForm, dir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts, err := ac.options(s.lg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case s.jobs <- struct{}{}:
+		defer func() { <-s.jobs }()
+	case <-r.Context().Done():
+		return
+	}
+
+	if _, err := convertPages(ac, s.lg, opts, ac.input); err != nil {
+		s.lg.Error("conversion failed", "err", err.Error())
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	f, err := os.Open(ac.outZip)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="vault.zip"`)
+	if _, err := io.Copy(w, f); err != nil {
+		s.lg.Error("send vault", "err", err.Error())
+	}
+}
+
+// config returns the configuration of a request, saving its uploads to
+// dir.
+func (s *server) config(form *multipart.Form, dir string) (appConfig, error) {
+	var ac appConfig
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	ac.addFlags(fs)
+
+	files := form.File[serveExportField]
+	if len(files) != 1 {
+		return ac, fmt.Errorf("upload one export in the %s field", serveExportField)
+	}
+
+	ext := strings.ToLower(filepath.Ext(files[0].Filename))
+	if ext != ".zip" {
+		ext = ".json"
+	}
+	input, err := saveUpload(files[0], filepath.Join(dir, "export"+ext))
+	if err != nil {
+		return ac, err
+	}
+
+	var args []string
+	names := make([]string, 0, len(form.Value))
+	for name := range form.Value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == serveFlagsField {
+			continue
+		}
+		for _, value := range form.Value[name] {
+			args = append(args, "-"+name+"="+value)
+		}
+	}
+	for _, value := range form.Value[serveFlagsField] {
+		args = append(args, strings.Fields(value)...)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return ac, fmt.Errorf("parse flags: %w", err)
+	}
+	if fs.NArg() > 0 {
+		return ac, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	var denied []string
+	fs.Visit(func(f *flag.Flag) {
+		if _, ok := serveDeniedFlags[f.Name]; ok || containsFlag(serveFileFlags, f.Name) {
+			denied = append(denied, "-"+f.Name)
+		}
+	})
+	if len(denied) > 0 {
+		return ac, fmt.Errorf("%s can't be set when converting on the server", strings.Join(denied, ", "))
+	}
+
+	for _, name := range serveFileFlags {
+		files := form.File[name]
+		if len(files) == 0 {
+			continue
+		}
+		path, err := saveUpload(files[0], filepath.Join(dir, name+".json"))
+		if err != nil {
+			return ac, err
+		}
+		if err := fs.Set(name, path); err != nil {
+			return ac, err
+		}
+	}
+
+	ac.input = input
+	ac.outZip = filepath.Join(dir, "vault.zip")
+
+	return ac, nil
+}
+
+func containsFlag(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// saveUpload copies an uploaded file to path.
+func saveUpload(fh *multipart.FileHeader, path string) (string, error) {
+	src, err := fh.Open()
+	if err != nil {
+		return "", fmt.Errorf("read upload %s: %w", fh.Filename, err)
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return "", fmt.Errorf("save upload %s: %w", fh.Filename, err)
+	}
+
+	return path, dst.Close()
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bryanl/goram2obs/pkg/convert"
+	"github.com/bryanl/goram2obs/pkg/roam"
+	"github.com/bryanl/goram2obs/pkg/vault"
+)
+
+const (
+	// tuiCommand browses the export and previews its notes interactively
+	// before running the conversion. Like preview, it takes the conversion
+	// flags, so it is handled by main.
+	tuiCommand = "tui"
+
+	// tuiMaxList is how many pages or problems are listed at once.
+	tuiMaxList = 50
+	// tuiWidth is the screen width used when $COLUMNS isn't set.
+	tuiWidth = 120
+)
+
+const tuiHelp = `Commands:
+  pages [regexp]      list pages, optionally those whose title matches
+  dailies [regexp]    list daily pages
+  problems            convert every page and list what the report would hold
+  show <title>        show a page's blocks next to its converted note
+  set <flag> [value]  change a conversion flag, such as "set style outline"
+  options             list the flags that were changed
+  run                 convert the export with the current flags
+  help                show this help
+  quit                leave without converting`
+
+// tui is an interactive session over a loaded export.
+type tui struct {
+	ac    *appConfig
+	lg    *logger
+	opts  convert.Options
+	pages []roam.Page
+	out   io.Writer
+}
+
+// runTUI loads the export once and reads commands from stdin until quit or
+// end of input. ac is shared with the flags, so set changes it.
+func runTUI(ac *appConfig, lg *logger) error {
+	if err := ac.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if ac.input == "-" {
+		return errors.New("tui reads its commands from stdin, so -i can't be -")
+	}
+
+	opts, err := ac.options(lg)
+	if err != nil {
+		return err
+	}
+
+	pages, err := ac.loadPages(ac.input)
+	if err != nil {
+		return fmt.Errorf("load input: %w", err)
+	}
+
+	t := &tui{ac: ac, lg: lg, opts: opts, pages: pages, out: os.Stdout}
+	fmt.Fprintf(t.out, "Loaded %d pages from %s. Type help for the commands.\n", len(pages), ac.input)
+
+	in := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Fprint(t.out, "> ")
+		if !in.Scan() {
+			fmt.Fprintln(t.out)
+			return in.Err()
+		}
+
+		fields := strings.Fields(in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		cmd, arg := fields[0], strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(in.Text()), fields[0]))
+
+		var err error
+		switch cmd {
+		case "pages":
+			err = t.list(arg, false)
+		case "dailies":
+			err = t.list(arg, true)
+		case "problems":
+			err = t.problems()
+		case "show":
+			err = t.show(arg)
+		case "set":
+			err = t.set(fields[1:])
+		case "options":
+			t.options()
+		case "run":
+			err = t.run()
+		case "help":
+			fmt.Fprintln(t.out, tuiHelp)
+		case "quit", "exit":
+			return nil
+		default:
+			err = fmt.Errorf("unknown command %q, type help for the commands", cmd)
+		}
+
+		if err != nil {
+			fmt.Fprintln(t.out, "error:", err)
+		}
+	}
+}
+
+// run converts the loaded pages with the current options and reports where
+// the vault was written.
+func (t *tui) run() error {
+	err := recordRun(*t.ac, t.lg, t.opts, t.ac.input, func(opts convert.Options) (convert.Stats, error) {
+		return writePages(*t.ac, t.lg, opts, t.ac.input, copyPages(t.pages))
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(t.out, "Wrote %s\n", t.ac.output())
+
+	return nil
+}
+
+// list prints the titles of the pages, or the daily pages, matching expr.
+// Daily pages are the ones titled with a Roam date.
+func (t *tui) list(expr string, dailies bool) error {
+	var re *regexp.Regexp
+	if expr != "" {
+		var err error
+		if re, err = regexp.Compile("(?i)" + expr); err != nil {
+			return err
+		}
+	}
+
+	var titles []string
+	for _, page := range t.pages {
+		if _, daily, _ := roam.ParseDate(page.Title); daily != dailies {
+			continue
+		}
+		if re != nil && !re.MatchString(page.Title) {
+			continue
+		}
+		titles = append(titles, page.Title)
+	}
+	sort.Strings(titles)
+
+	for i, title := range titles {
+		if i == tuiMaxList {
+			fmt.Fprintf(t.out, "... and %d more\n", len(titles)-tuiMaxList)
+			break
+		}
+		fmt.Fprintln(t.out, title)
+	}
+	fmt.Fprintf(t.out, "%d pages\n", len(titles))
+
+	return nil
+}
+
+// convert converts the pages selected by opts into memory.
+func (t *tui) convert(opts convert.Options) (*convert.Converter, *vault.Memory, error) {
+	opts.Logger = nil
+	opts.Progress = newProgressBar(progressModeNone, os.Stderr)
+
+	c, err := convert.New(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mem := vault.NewMemory()
+	if err := c.Convert(copyPages(t.pages), mem); err != nil {
+		return nil, nil, err
+	}
+
+	return c, mem, nil
+}
+
+// copyPages copies pages and their blocks, since a conversion changes the
+// pages it is given, such as the titles of daily pages.
+func copyPages(pages []roam.Page) []roam.Page {
+	out := make([]roam.Page, len(pages))
+	for i, page := range pages {
+		out[i] = page
+		out[i].RawChildren = copyBlocks(page.RawChildren)
+	}
+
+	return out
+}
+
+func copyBlocks(blocks []roam.Child) []roam.Child {
+	if blocks == nil {
+		return nil
+	}
+
+	out := make([]roam.Child, len(blocks))
+	for i, block := range blocks {
+		out[i] = block
+		out[i].RawChildren = copyBlocks(block.RawChildren)
+	}
+
+	return out
+}
+
+// problems converts the export and lists the pages and blocks its report
+// would call out.
+func (t *tui) problems() error {
+	c, _, err := t.convert(t.opts)
+	if err != nil {
+		return err
+	}
+
+	r := c.Report()
+	var found bool
+	section := func(name string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		found = true
+		fmt.Fprintf(t.out, "%s (%d)\n", name, len(lines))
+		for i, line := range lines {
+			if i == tuiMaxList {
+				fmt.Fprintf(t.out, "  ... and %d more\n", len(lines)-tuiMaxList)
+				break
+			}
+			fmt.Fprintln(t.out, "  "+line)
+		}
+	}
+
+	var lines []string
+	for _, e := range r.Quarantined {
+		lines = append(lines, e.Page+": "+e.Reason)
+	}
+	section("Quarantined pages", lines)
+
+	lines = nil
+	for _, col := range r.Collisions {
+		lines = append(lines, strings.Join(col.Titles, ", "))
+	}
+	section("Filename collisions", lines)
+
+	lines = nil
+	for _, e := range r.Skipped {
+		lines = append(lines, e.Page+": "+e.Reason)
+	}
+	section("Skipped pages", lines)
+
+	lines = nil
+	for _, ref := range r.Unresolved {
+		lines = append(lines, fmt.Sprintf("((%s)) in %s, block %s", ref.UID, ref.Page, ref.BlockUID))
+	}
+	section("Unresolved block refs", lines)
+
+	lines = nil
+	for _, ch := range r.RefChains {
+		lines = append(lines, fmt.Sprintf("%s, block %s: %s", ch.Page, ch.BlockUID, ch.Reason))
+	}
+	section("Block ref chains", lines)
+
+	lines = nil
+	for _, q := range r.Queries {
+		lines = append(lines, fmt.Sprintf("%s, block %s: %s", q.Page, q.BlockUID, q.Query))
+	}
+	section("Queries", lines)
+
+	lines = nil
+	for _, sb := range r.SmartBlocks {
+		lines = append(lines, fmt.Sprintf("%s, block %s: %s", sb.Page, sb.BlockUID, strings.Join(sb.Found, " ")))
+	}
+	section("SmartBlocks", lines)
+
+	if !found {
+		fmt.Fprintln(t.out, "No problems found")
+	}
+
+	return nil
+}
+
+// show prints the blocks of the page titled title on the left and its
+// converted note on the right.
+func (t *tui) show(title string) error {
+	if title == "" {
+		return errors.New("show needs a page title")
+	}
+
+	page := t.page(title)
+	if page == nil {
+		return fmt.Errorf("no page titled %q", title)
+	}
+
+	opts := t.opts
+	opts.Pages = []string{page.Title}
+	c, mem, err := t.convert(opts)
+	if err != nil {
+		return err
+	}
+
+	var note []string
+	for _, path := range c.Notes() {
+		data, err := mem.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		note = append(note, "==> "+path+" <==")
+		note = append(note, strings.Split(strings.TrimRight(string(data), "\n"), "\n")...)
+	}
+	if len(note) == 0 {
+		note = []string{"(no note written, see problems)"}
+	}
+
+	width := tuiWidth
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 20 {
+		width = n
+	}
+	col := (width - 3) / 2
+
+	blocks := append([]string{"==> " + page.Title + " <=="}, outlineBlocks(page, 0)...)
+	for i := 0; i < len(blocks) || i < len(note); i++ {
+		var left, right string
+		if i < len(blocks) {
+			left = blocks[i]
+		}
+		if i < len(note) {
+			right = note[i]
+		}
+		fmt.Fprintf(t.out, "%s | %s\n", padColumn(left, col), fitColumn(right, col))
+	}
+
+	return nil
+}
+
+// page finds the page titled title, ignoring case when no title matches
+// exactly.
+func (t *tui) page(title string) *roam.Page {
+	for i := range t.pages {
+		if t.pages[i].Title == title {
+			return &t.pages[i]
+		}
+	}
+	for i := range t.pages {
+		if strings.EqualFold(t.pages[i].Title, title) {
+			return &t.pages[i]
+		}
+	}
+
+	return nil
+}
+
+// outlineBlocks returns the blocks below parent as Roam shows them, one line
+// per line of block text.
+func outlineBlocks(parent roam.Parent, level int) []string {
+	var lines []string
+	indent := strings.Repeat("  ", level)
+
+	for _, child := range parent.Children() {
+		for i, line := range strings.Split(child.String, "\n") {
+			if i == 0 {
+				lines = append(lines, indent+"• "+line)
+				continue
+			}
+			lines = append(lines, indent+"  "+line)
+		}
+		lines = append(lines, outlineBlocks(&child, level+1)...)
+	}
+
+	return lines
+}
+
+// fitColumn cuts s to width runes.
+func fitColumn(s string, width int) string {
+	s = strings.ReplaceAll(s, "\t", "    ")
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+
+	return string([]rune(s)[:width-1]) + "…"
+}
+
+// padColumn cuts or pads s to width runes.
+func padColumn(s string, width int) string {
+	s = fitColumn(s, width)
+	return s + strings.Repeat(" ", width-utf8.RuneCountInString(s))
+}
+
+// set changes a flag and rebuilds the options, restoring the flag when the
+// options it gives are invalid. A boolean flag given without a value is set
+// to true. The values of a repeatable flag are replaced rather than added
+// to, and an empty value clears them.
+func (t *tui) set(args []string) error {
+	if len(args) == 0 {
+		return errors.New("set needs a flag name")
+	}
+
+	name := strings.TrimLeft(args[0], "-")
+	f := flag.Lookup(name)
+	if f == nil {
+		return fmt.Errorf("unknown flag %q", name)
+	}
+
+	value := strings.Join(args[1:], " ")
+	if value == "" {
+		if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && bf.IsBoolFlag() {
+			value = "true"
+		}
+	}
+
+	old := f.Value.String()
+	restore := func() error { return flag.Set(name, old) }
+	switch v := f.Value.(type) {
+	case *stringList:
+		saved := *v
+		*v, restore = nil, func() error { *v = saved; return nil }
+	case *repeatedFlag:
+		saved := *v
+		*v, restore = nil, func() error { *v = saved; return nil }
+	}
+
+	err := flag.Set(name, value)
+	if err == nil {
+		var opts convert.Options
+		if opts, err = t.ac.options(t.lg); err == nil {
+			t.opts = opts
+			fmt.Fprintf(t.out, "-%s=%s\n", name, f.Value.String())
+			return nil
+		}
+	}
+
+	if rerr := restore(); rerr != nil {
+		return fmt.Errorf("%v (restoring -%s: %w)", err, name, rerr)
+	}
+
+	return err
+}
+
+// options prints the flags set on the command line or with set.
+func (t *tui) options() {
+	set := setFlags()
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(t.out, "-%s=%s\n", name, set[name])
+	}
+}
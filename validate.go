@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/bryanl/goram2obs/pkg/vault"
+)
+
+// runValidate checks the links in a converted vault.
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	dir := fs.String("d", "", "Vault directory")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *dir == "" {
+		return errors.New("vault directory is blank")
+	}
+
+	r, err := vault.Validate(*dir)
+	if err != nil {
+		return fmt.Errorf("validate %s: %w", *dir, err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, p := range r.Problems {
+			fmt.Fprintf(tw, "%s:%d\t%s\t%s\n", p.File, p.Line, p.Kind, p.Link)
+		}
+		fmt.Fprintf(tw, "%d notes, %d links, %d problems\n", r.Notes, r.Links, len(r.Problems))
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(r.Problems) > 0 {
+		return fmt.Errorf("%d broken links", len(r.Problems))
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/bryanl/goram2obs/pkg/convert"
+	"github.com/bryanl/goram2obs/pkg/roam"
+)
+
+// exportState identifies a version of an export file.
+type exportState struct {
+	size    int64
+	modTime time.Time
+}
+
+// watch converts every export that appears in ac.watch until
+// interrupted. Exports already in the directory are ignored. A file is only
+// converted once its size and modification time stop changing, so exports
+// that are still being downloaded are not read.
+func watch(ac appConfig, lg *logger, opts convert.Options) error {
+	seen, err := scanExports(ac.watch)
+	if err != nil {
+		return err
+	}
+	pending := map[string]exportState{}
+
+	lg.Info("watching for exports", "dir", ac.watch, "interval", ac.watchInterval)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(ac.watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-interrupt:
+			lg.Info("stopped watching", "dir", ac.watch)
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := scanExports(ac.watch)
+		if err != nil {
+			lg.Error("scan watch directory", "dir", ac.watch, "error", err.Error())
+			continue
+		}
+
+		names := make([]string, 0, len(current))
+		for name := range current {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			state := current[name]
+			if prev, ok := seen[name]; ok && prev == state {
+				delete(pending, name)
+				continue
+			}
+
+			if prev, ok := pending[name]; !ok || prev != state {
+				pending[name] = state
+				continue
+			}
+
+			delete(pending, name)
+			seen[name] = state
+
+			if err := convertFile(ac, lg, opts, name); err != nil {
+				lg.Error("convert export", "input", name, "error", err.Error())
+			}
+		}
+	}
+}
+
+// scanExports returns the files in dir that a registered importer
+// recognizes.
+func scanExports(dir string) (map[string]exportState, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	exports := map[string]exportState{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		p := filepath.Join(dir, entry.Name())
+		if _, err := roam.Importers.Detect(p); err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		exports[p] = exportState{size: info.Size(), modTime: info.ModTime()}
+	}
+
+	return exports, nil
+}